@@ -0,0 +1,312 @@
+/*
+Package aggregate sums tracked time by day, week, month, project, client, tag
+and description.
+
+An aggregation is built up from a list of time entries and summed into a tree:
+
+	tree := aggregate.Aggregate(entries).
+		ForAccount(account).
+		By(aggregate.Day, aggregate.Project).
+		Sum()
+
+Running entries are counted up to the current time. Entries that cross
+midnight in the aggregation's time zone are split between the days they span.
+Entries with several tags are counted once under each of their tags, so the
+children of a Tag level may add up to more than their parent.
+*/
+package aggregate
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jason0x43/go-toggl"
+)
+
+// Dimension is something time can be grouped by.
+type Dimension int
+
+// Dimensions
+const (
+	Day Dimension = iota
+	Week
+	Month
+	Workspace
+	Client
+	Project
+	Tag
+	Description
+)
+
+var dimensionNames = map[Dimension]string{
+	Day:         "day",
+	Week:        "week",
+	Month:       "month",
+	Workspace:   "workspace",
+	Client:      "client",
+	Project:     "project",
+	Tag:         "tag",
+	Description: "description",
+}
+
+func (d Dimension) String() string {
+	return dimensionNames[d]
+}
+
+// isTime returns true for dimensions that split entries at day boundaries.
+func (d Dimension) isTime() bool {
+	return d == Day || d == Week || d == Month
+}
+
+// Aggregation describes how to group a list of time entries.
+type Aggregation struct {
+	entries   []toggl.TimeEntry
+	dims      []Dimension
+	loc       *time.Location
+	weekStart time.Weekday
+	index     toggl.Index
+	now       time.Time
+}
+
+// Aggregate starts an aggregation of time entries. By default days are
+// determined in the local time zone and weeks start on Sunday.
+func Aggregate(entries []toggl.TimeEntry) Aggregation {
+	return Aggregation{entries: entries, loc: time.Local}
+}
+
+// ForAccount uses an account's time zone, beginning of the week, and related
+// data for names.
+func (a Aggregation) ForAccount(account toggl.Account) Aggregation {
+	if loc, err := time.LoadLocation(account.Timezone); err == nil && account.Timezone != "" {
+		a.loc = loc
+	}
+	a.weekStart = time.Weekday(account.BeginningOfWeek % 7)
+	a.index = toggl.NewIndex(account)
+	return a
+}
+
+// In sets the time zone used to determine days.
+func (a Aggregation) In(loc *time.Location) Aggregation {
+	a.loc = loc
+	return a
+}
+
+// WeekStartsOn sets the first day of the week.
+func (a Aggregation) WeekStartsOn(day time.Weekday) Aggregation {
+	a.weekStart = day
+	return a
+}
+
+// WithIndex sets the index used to resolve project, client and workspace
+// names.
+func (a Aggregation) WithIndex(index toggl.Index) Aggregation {
+	a.index = index
+	return a
+}
+
+// At sets the time running entries are counted up to. It defaults to the time
+// Sum is called.
+func (a Aggregation) At(now time.Time) Aggregation {
+	a.now = now
+	return a
+}
+
+// By sets the dimensions to group by, from the top of the tree down.
+func (a Aggregation) By(dims ...Dimension) Aggregation {
+	a.dims = dims
+	return a
+}
+
+// Node is a group of tracked time.
+type Node struct {
+	// Dimension is the dimension this node groups by. It is meaningless for
+	// the root node.
+	Dimension Dimension
+
+	// Key identifies the group within its dimension: a date for time
+	// dimensions, an ID for workspaces, clients and projects, and the value
+	// itself for tags and descriptions. Keys of entries without a client,
+	// project or tag are empty.
+	Key string
+
+	// Label is a human readable name for the group.
+	Label string
+
+	Duration time.Duration
+
+	// Entries contains the IDs of the time entries in the group.
+	Entries []int
+
+	Children []*Node
+}
+
+// Child returns the child with a given key, or nil.
+func (n *Node) Child(key string) *Node {
+	for _, c := range n.Children {
+		if c.Key == key {
+			return c
+		}
+	}
+	return nil
+}
+
+// Walk calls fn for the node and each of its descendants, depth first. The
+// path contains the node's ancestors, starting with the root.
+func (n *Node) Walk(fn func(path []*Node, node *Node)) {
+	n.walk(nil, fn)
+}
+
+func (n *Node) walk(path []*Node, fn func([]*Node, *Node)) {
+	fn(path, n)
+	path = append(path, n)
+	for _, c := range n.Children {
+		c.walk(path, fn)
+	}
+}
+
+// segment is the part of a time entry that falls within one group.
+type segment struct {
+	entry    *toggl.TimeEntry
+	start    time.Time
+	duration time.Duration
+}
+
+// Sum groups the entries and totals their durations.
+func (a Aggregation) Sum() *Node {
+	now := a.now
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	splitDays := false
+	for _, d := range a.dims {
+		splitDays = splitDays || d.isTime()
+	}
+
+	var segments []segment
+	for i := range a.entries {
+		e := &a.entries[i]
+		if e.Start == nil {
+			continue
+		}
+
+		d := time.Duration(e.Duration) * time.Second
+		if e.IsRunning() {
+			d = now.Sub(*e.Start)
+		}
+		if d <= 0 {
+			continue
+		}
+
+		if !splitDays {
+			segments = append(segments, segment{entry: e, start: *e.Start, duration: d})
+			continue
+		}
+
+		start := e.Start.In(a.loc)
+		end := start.Add(d)
+		for start.Before(end) {
+			y, m, day := start.Date()
+			midnight := time.Date(y, m, day+1, 0, 0, 0, 0, a.loc)
+			stop := end
+			if midnight.Before(end) {
+				stop = midnight
+			}
+			segments = append(segments, segment{entry: e, start: start, duration: stop.Sub(start)})
+			start = stop
+		}
+	}
+
+	root := &Node{}
+	a.group(root, segments, a.dims)
+	return root
+}
+
+func (a Aggregation) group(node *Node, segments []segment, dims []Dimension) {
+	seen := map[int]bool{}
+	for _, s := range segments {
+		node.Duration += s.duration
+		if !seen[s.entry.ID] {
+			seen[s.entry.ID] = true
+			node.Entries = append(node.Entries, s.entry.ID)
+		}
+	}
+	sort.Ints(node.Entries)
+
+	if len(dims) == 0 {
+		return
+	}
+
+	dim := dims[0]
+	groups := map[string][]segment{}
+	labels := map[string]string{}
+	for _, s := range segments {
+		for _, kl := range a.keys(dim, s) {
+			groups[kl[0]] = append(groups[kl[0]], s)
+			labels[kl[0]] = kl[1]
+		}
+	}
+
+	for key, group := range groups {
+		child := &Node{Dimension: dim, Key: key, Label: labels[key]}
+		a.group(child, group, dims[1:])
+		node.Children = append(node.Children, child)
+	}
+
+	sort.Slice(node.Children, func(i, j int) bool {
+		return node.Children[i].Key < node.Children[j].Key
+	})
+}
+
+// keys returns the key and label pairs of the groups a segment belongs to in
+// a dimension.
+func (a Aggregation) keys(dim Dimension, s segment) [][2]string {
+	e := s.entry
+	start := s.start.In(a.loc)
+
+	switch dim {
+	case Day:
+		day := start.Format("2006-01-02")
+		return [][2]string{{day, day}}
+	case Week:
+		y, m, d := start.Date()
+		offset := (int(start.Weekday()) - int(a.weekStart) + 7) % 7
+		week := time.Date(y, m, d-offset, 0, 0, 0, 0, a.loc).Format("2006-01-02")
+		return [][2]string{{week, "Week of " + week}}
+	case Month:
+		return [][2]string{{start.Format("2006-01"), start.Format("January 2006")}}
+	case Workspace:
+		return [][2]string{{strconv.Itoa(e.Wid), a.index.Workspaces[e.Wid].Name}}
+	case Client:
+		if e.Pid != nil {
+			if p, ok := a.index.Projects[*e.Pid]; ok && p.Cid != nil {
+				return [][2]string{{strconv.Itoa(*p.Cid), a.index.ClientName(e.Pid)}}
+			}
+		}
+		return [][2]string{{"", "(no client)"}}
+	case Project:
+		if e.Pid == nil {
+			return [][2]string{{"", "(no project)"}}
+		}
+		return [][2]string{{strconv.Itoa(*e.Pid), a.index.ProjectName(e.Pid)}}
+	case Tag:
+		if len(e.Tags) == 0 {
+			return [][2]string{{"", "(no tag)"}}
+		}
+		var keys [][2]string
+		seen := map[string]bool{}
+		for _, t := range e.Tags {
+			if !seen[t] {
+				seen[t] = true
+				keys = append(keys, [2]string{t, t})
+			}
+		}
+		return keys
+	default:
+		if e.Description == "" {
+			return [][2]string{{"", "(no description)"}}
+		}
+		return [][2]string{{e.Description, e.Description}}
+	}
+}
@@ -0,0 +1,171 @@
+package daemon
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+)
+
+// Client is a connection to a daemon.
+type Client struct {
+	nc      net.Conn
+	mu      sync.Mutex
+	enc     *json.Encoder
+	nextID  int
+	pending map[string]chan Response
+	err     error
+
+	// Notifications receives the timer notifications sent after Subscribe.
+	// It's closed when the connection is.
+	Notifications chan Notification
+}
+
+// Dial connects to a daemon listening on a Unix socket at path.
+func Dial(path string) (*Client, error) {
+	nc, err := net.Dial("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Client{
+		nc:            nc,
+		enc:           json.NewEncoder(nc),
+		pending:       map[string]chan Response{},
+		Notifications: make(chan Notification, 16),
+	}
+	go c.read()
+	return c, nil
+}
+
+// Close closes the connection.
+func (c *Client) Close() error {
+	return c.nc.Close()
+}
+
+// Call calls a method with params and decodes its result into result, if it's
+// not nil.
+func (c *Client) Call(method string, params, result interface{}) error {
+	req := Request{Version: "2.0", Method: method}
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		req.Params = data
+	}
+
+	ch := make(chan Response, 1)
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return c.err
+	}
+	c.nextID++
+	id := strconv.Itoa(c.nextID)
+	req.ID = json.RawMessage(id)
+	c.pending[id] = ch
+	err := c.enc.Encode(req)
+	if err != nil {
+		delete(c.pending, id)
+	}
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	resp, ok := <-ch
+	if !ok {
+		return fmt.Errorf("connection closed")
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	if result != nil {
+		return json.Unmarshal(resp.Result, result)
+	}
+	return nil
+}
+
+// Status returns the running timer.
+func (c *Client) Status() (Status, error) {
+	var status Status
+	err := c.Call("status", nil, &status)
+	return status, err
+}
+
+// Start starts a timer. It fails if a timer is already running.
+func (c *Client) Start(params TimerParams) (Status, error) {
+	var status Status
+	err := c.Call("start", params, &status)
+	return status, err
+}
+
+// Stop stops the running timer.
+func (c *Client) Stop() (Status, error) {
+	var status Status
+	err := c.Call("stop", nil, &status)
+	return status, err
+}
+
+// Continue restarts the entry with an ID, or the most recent entry if id is
+// zero, stopping the running timer.
+func (c *Client) Continue(id int) (Status, error) {
+	var status Status
+	err := c.Call("continue", ContinueParams{ID: id}, &status)
+	return status, err
+}
+
+// Switch stops the running timer and starts a new one.
+func (c *Client) Switch(params TimerParams) (Status, error) {
+	var status Status
+	err := c.Call("switch", params, &status)
+	return status, err
+}
+
+// Subscribe asks the daemon to send timer notifications to Notifications.
+func (c *Client) Subscribe() error {
+	return c.Call("subscribe", nil, nil)
+}
+
+func (c *Client) read() {
+	scanner := bufio.NewScanner(c.nc)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var resp Response
+		if err := json.Unmarshal(scanner.Bytes(), &resp); err != nil {
+			continue
+		}
+
+		if len(resp.ID) == 0 {
+			var n Notification
+			if resp.Method == "timer" && json.Unmarshal(resp.Params, &n) == nil {
+				select {
+				case c.Notifications <- n:
+				default:
+					// drop notifications nobody is reading
+				}
+			}
+			continue
+		}
+
+		c.mu.Lock()
+		ch := c.pending[string(resp.ID)]
+		delete(c.pending, string(resp.ID))
+		c.mu.Unlock()
+		if ch != nil {
+			ch <- resp
+		}
+	}
+
+	c.mu.Lock()
+	c.err = fmt.Errorf("connection closed")
+	for id, ch := range c.pending {
+		close(ch)
+		delete(c.pending, id)
+	}
+	c.mu.Unlock()
+	close(c.Notifications)
+}
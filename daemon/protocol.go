@@ -0,0 +1,125 @@
+/*
+Package daemon shares a single Toggl session between local clients.
+
+A Server holds one toggl.Session, caches the account and the running timer,
+and serves a small JSON-RPC 2.0 API over a Unix domain socket. Messages are
+JSON objects separated by newlines. The methods are:
+
+	status     the running timer, if any
+	account    the cached account, including projects, clients and tags
+	refresh    reload the cached account and running timer
+	start      start a timer; fails if one is already running
+	stop       stop the running timer
+	continue   restart an earlier entry (params: {"id": ID}, or the latest)
+	switch     stop the running timer, if any, and start a new one
+	subscribe  receive "timer" notifications on this connection
+
+The start and switch methods take a TimerParams object. Calls to Toggl are
+spaced at least MinRequestInterval apart, so clients share one rate-limited
+connection no matter how many of them there are.
+
+A session with the daemon looks like this:
+
+	--> {"jsonrpc":"2.0","id":1,"method":"subscribe"}
+	<-- {"jsonrpc":"2.0","id":1,"result":true}
+	--> {"jsonrpc":"2.0","id":2,"method":"start","params":{"description":"Reviews"}}
+	<-- {"jsonrpc":"2.0","method":"timer","params":{"kind":"started","entry":{...},"elapsed":0}}
+	<-- {"jsonrpc":"2.0","id":2,"result":{"running":true,"entry":{...},"elapsed":0}}
+*/
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/jason0x43/go-toggl"
+)
+
+// JSON-RPC error codes
+const (
+	ParseError     = -32700
+	InvalidRequest = -32600
+	MethodNotFound = -32601
+	InvalidParams  = -32602
+	ServerError    = -32000
+)
+
+// Request is a JSON-RPC request. Requests without an ID are notifications and
+// get no response.
+type Request struct {
+	Version string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// Response is a JSON-RPC response, or a notification sent by the server when
+// ID is empty and Method is set.
+type Response struct {
+	Version string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+
+	Method string          `json:"method,omitempty"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+// Error is a JSON-RPC error.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s (%d)", e.Message, e.Code)
+}
+
+// Status is the result of the status, stop, start, continue and switch
+// methods.
+type Status struct {
+	Running bool             `json:"running"`
+	Entry   *toggl.TimeEntry `json:"entry,omitempty"`
+
+	// Elapsed is how long the timer has been running, in seconds.
+	Elapsed int64 `json:"elapsed"`
+}
+
+// TimerParams describes a timer to start. If Wid is zero the account's first
+// workspace is used.
+type TimerParams struct {
+	Description string   `json:"description"`
+	Wid         int      `json:"workspace_id,omitempty"`
+	Pid         *int     `json:"project_id,omitempty"`
+	Tid         *int     `json:"task_id,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Billable    bool     `json:"billable,omitempty"`
+}
+
+// ContinueParams selects the entry to continue. If ID is zero the most recent
+// entry is continued.
+type ContinueParams struct {
+	ID int `json:"id,omitempty"`
+}
+
+// Notification is the parameter of "timer" notifications sent to subscribed
+// connections when the running timer starts, stops or changes.
+type Notification struct {
+	// Kind is "started", "stopped" or "changed".
+	Kind    string          `json:"kind"`
+	Entry   toggl.TimeEntry `json:"entry"`
+	Changed []string        `json:"changed,omitempty"`
+	Elapsed int64           `json:"elapsed"`
+}
+
+// DefaultSocketPath returns the socket path used when none is given:
+// toggl.sock in $XDG_RUNTIME_DIR, or a per-user file in the temporary
+// directory.
+func DefaultSocketPath() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "toggl.sock")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("toggl-%d.sock", os.Getuid()))
+}
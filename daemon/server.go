@@ -0,0 +1,490 @@
+package daemon
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/jason0x43/go-toggl"
+)
+
+// Server serves the daemon API for a Toggl session.
+type Server struct {
+	Session *toggl.Session
+
+	// Interval is how often the running timer is polled.
+	Interval time.Duration
+
+	// MinRequestInterval is the shortest time between calls to Toggl. A call
+	// may make several requests, such as stopping one timer and starting
+	// another.
+	MinRequestInterval time.Duration
+
+	mu      sync.Mutex
+	current toggl.TimeEntry
+	account toggl.Account
+	conns   map[*conn]bool
+
+	apiMu       sync.Mutex
+	lastRequest time.Time
+}
+
+// NewServer returns a server for a session that polls the running timer once
+// a minute and makes at most one request to Toggl per second.
+func NewServer(session *toggl.Session) *Server {
+	return &Server{
+		Session:            session,
+		Interval:           time.Minute,
+		MinRequestInterval: time.Second,
+	}
+}
+
+// Listen listens on a Unix socket at path, replacing a stale socket left by a
+// daemon that's no longer running.
+func Listen(path string) (net.Listener, error) {
+	if c, err := net.Dial("unix", path); err == nil {
+		c.Close()
+		return nil, fmt.Errorf("a daemon is already listening on %s", path)
+	}
+	os.Remove(path)
+
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		l.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// Serve loads the account and running timer, then serves connections from l
+// until ctx is done. l is closed when Serve returns.
+func (s *Server) Serve(ctx context.Context, l net.Listener) error {
+	defer l.Close()
+
+	s.mu.Lock()
+	s.conns = map[*conn]bool{}
+	s.mu.Unlock()
+
+	if err := s.refresh(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go s.poll(ctx)
+	go func() {
+		<-ctx.Done()
+		l.Close()
+	}()
+
+	for {
+		nc, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		go s.handle(ctx, nc)
+	}
+}
+
+// api calls fn, spacing calls at least MinRequestInterval apart.
+func (s *Server) api(fn func() error) error {
+	s.apiMu.Lock()
+	defer s.apiMu.Unlock()
+
+	if wait := time.Until(s.lastRequest.Add(s.MinRequestInterval)); wait > 0 {
+		time.Sleep(wait)
+	}
+	defer func() { s.lastRequest = time.Now() }()
+	return fn()
+}
+
+func (s *Server) refresh() error {
+	var account toggl.Account
+	var current toggl.TimeEntry
+	err := s.api(func() (err error) {
+		account, err = s.Session.GetAccount()
+		return
+	})
+	if err == nil {
+		err = s.api(func() (err error) {
+			current, err = s.Session.GetCurrentTimeEntry()
+			return
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.account = account
+	s.mu.Unlock()
+	s.setCurrent(current)
+	return nil
+}
+
+// poll keeps the cached timer up to date, backing off while polling fails.
+func (s *Server) poll(ctx context.Context) {
+	delay := s.Interval
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+
+		var current toggl.TimeEntry
+		err := s.api(func() (err error) {
+			current, err = s.Session.GetCurrentTimeEntry()
+			return
+		})
+		if err != nil {
+			if delay < 32*s.Interval {
+				delay *= 2
+			}
+			continue
+		}
+		delay = s.Interval
+		s.setCurrent(current)
+	}
+}
+
+// setCurrent updates the cached timer and notifies subscribers of any change.
+// Messages are queued on each connection, so a slow client can't hold up
+// other calls.
+func (s *Server) setCurrent(current toggl.TimeEntry) {
+	s.mu.Lock()
+	var messages []Response
+	for _, event := range toggl.DiffTimers(s.current, current, time.Now()) {
+		if event.Kind == toggl.TimerTick {
+			continue
+		}
+		params, _ := json.Marshal(Notification{
+			Kind:    event.Kind.String(),
+			Entry:   event.Entry,
+			Changed: event.Changed,
+			Elapsed: int64(event.Elapsed / time.Second),
+		})
+		messages = append(messages, Response{Version: "2.0", Method: "timer", Params: params})
+	}
+	var subscribers []*conn
+	if len(messages) > 0 {
+		for c := range s.conns {
+			if c.subscribed {
+				subscribers = append(subscribers, c)
+			}
+		}
+	}
+	s.current = current
+	s.mu.Unlock()
+
+	for _, c := range subscribers {
+		for _, m := range messages {
+			c.send(m)
+		}
+	}
+}
+
+func (s *Server) status() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.current.ID == 0 {
+		return Status{}
+	}
+	entry := s.current
+	return Status{
+		Running: true,
+		Entry:   &entry,
+		Elapsed: int64(time.Since(entry.StartTime()) / time.Second),
+	}
+}
+
+// conn is a client connection.
+type conn struct {
+	nc         net.Conn
+	subscribed bool
+
+	mu     sync.Mutex
+	out    chan Response
+	closed bool
+}
+
+// connQueueSize is the number of messages that may wait to be written to a
+// connection.
+const connQueueSize = 64
+
+func newConn(nc net.Conn) *conn {
+	c := &conn{nc: nc, out: make(chan Response, connQueueSize)}
+	go c.write()
+	return c
+}
+
+// send queues a message for the connection. Clients that don't read their
+// messages are given up on rather than allowed to block the server.
+func (c *conn) send(r Response) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
+	select {
+	case c.out <- r:
+	default:
+		c.nc.Close()
+	}
+}
+
+// close stops the connection's writer once queued messages are written.
+func (c *conn) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.closed {
+		c.closed = true
+		close(c.out)
+	}
+}
+
+// write writes queued messages to the connection, and closes it when the
+// connection is closed.
+func (c *conn) write() {
+	defer c.nc.Close()
+	enc := json.NewEncoder(c.nc)
+	for r := range c.out {
+		c.nc.SetWriteDeadline(time.Now().Add(5 * time.Second))
+		if err := enc.Encode(r); err != nil {
+			c.nc.Close()
+		}
+	}
+}
+
+func (s *Server) handle(ctx context.Context, nc net.Conn) {
+	c := newConn(nc)
+	s.mu.Lock()
+	s.conns[c] = true
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			nc.Close()
+		case <-done:
+		}
+	}()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, c)
+		s.mu.Unlock()
+		c.close()
+	}()
+
+	scanner := bufio.NewScanner(nc)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var req Request
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			// the request's ID is unknown, which JSON-RPC gives as null
+			c.send(Response{
+				Version: "2.0",
+				ID:      json.RawMessage("null"),
+				Error:   &Error{Code: ParseError, Message: err.Error()},
+			})
+			continue
+		}
+
+		result, err := s.dispatch(c, req)
+		if len(req.ID) == 0 {
+			continue
+		}
+
+		resp := Response{Version: "2.0", ID: req.ID}
+		if err != nil {
+			var rpcErr *Error
+			if !errors.As(err, &rpcErr) {
+				rpcErr = &Error{Code: ServerError, Message: err.Error()}
+			}
+			resp.Error = rpcErr
+		} else if resp.Result, err = json.Marshal(result); err != nil {
+			resp.Error = &Error{Code: ServerError, Message: err.Error()}
+		}
+		c.send(resp)
+	}
+}
+
+func (s *Server) dispatch(c *conn, req Request) (interface{}, error) {
+	decode := func(v interface{}) error {
+		if len(req.Params) == 0 {
+			return nil
+		}
+		if err := json.Unmarshal(req.Params, v); err != nil {
+			return &Error{Code: InvalidParams, Message: err.Error()}
+		}
+		return nil
+	}
+
+	switch req.Method {
+	case "status":
+		return s.status(), nil
+
+	case "account":
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.account, nil
+
+	case "refresh":
+		if err := s.refresh(); err != nil {
+			return nil, err
+		}
+		return s.status(), nil
+
+	case "subscribe":
+		s.mu.Lock()
+		c.subscribed = true
+		s.mu.Unlock()
+		return true, nil
+
+	case "start", "switch":
+		var params TimerParams
+		if err := decode(&params); err != nil {
+			return nil, err
+		}
+		if req.Method == "start" {
+			return s.start(s.timerEntry(params))
+		}
+		return s.switchTo(s.timerEntry(params))
+
+	case "stop":
+		// the cached timer may be out of date, so the running timer is
+		// fetched again before it's stopped
+		err := s.api(func() error {
+			current, err := s.Session.GetCurrentTimeEntry()
+			if err != nil || current.ID == 0 {
+				return err
+			}
+			_, err = s.Session.StopTimeEntry(current)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		s.setCurrent(toggl.TimeEntry{})
+		return s.status(), nil
+
+	case "continue":
+		var params ContinueParams
+		if err := decode(&params); err != nil {
+			return nil, err
+		}
+		entry, err := s.findEntry(params.ID)
+		if err != nil {
+			return nil, err
+		}
+		return s.switchTo(entry)
+
+	default:
+		return nil, &Error{Code: MethodNotFound, Message: "unknown method " + req.Method}
+	}
+}
+
+// start starts a timer like next. The cached timer may be out of date, so
+// Toggl is asked whether a timer is running, and if one is a
+// *toggl.RunningTimerError is returned.
+func (s *Server) start(next toggl.TimeEntry) (Status, error) {
+	now := time.Now()
+	next.ID = 0
+	next.Start = &now
+	next.Stop = nil
+	next.Duration = -1
+
+	var started toggl.TimeEntry
+	err := s.api(func() (err error) {
+		started, err = s.Session.CreateTimeEntry(next)
+		return
+	})
+	var running *toggl.RunningTimerError
+	if errors.As(err, &running) {
+		s.setCurrent(running.Running)
+	}
+	if err != nil {
+		return Status{}, err
+	}
+	s.setCurrent(started)
+	return s.status(), nil
+}
+
+// switchTo stops the running timer and starts one like next.
+func (s *Server) switchTo(next toggl.TimeEntry) (Status, error) {
+	var started toggl.TimeEntry
+	err := s.api(func() (err error) {
+		_, started, err = s.Session.SwitchTimer(next)
+		return
+	})
+	if err != nil {
+		return Status{}, err
+	}
+	s.setCurrent(started)
+	return s.status(), nil
+}
+
+func (s *Server) timerEntry(params TimerParams) toggl.TimeEntry {
+	entry := toggl.TimeEntry{
+		Description: params.Description,
+		Wid:         params.Wid,
+		Pid:         params.Pid,
+		Tid:         params.Tid,
+		Tags:        params.Tags,
+		Billable:    params.Billable,
+	}
+
+	if entry.Wid == 0 {
+		s.mu.Lock()
+		if len(s.account.Workspaces) > 0 {
+			entry.Wid = s.account.Workspaces[0].ID
+		}
+		s.mu.Unlock()
+	}
+	return entry
+}
+
+// findEntry returns the entry with an ID from the last two weeks, or the most
+// recent entry if id is zero.
+func (s *Server) findEntry(id int) (toggl.TimeEntry, error) {
+	var entries []toggl.TimeEntry
+	now := time.Now()
+	err := s.api(func() (err error) {
+		entries, err = s.Session.GetTimeEntries(now.AddDate(0, 0, -14), now.Add(time.Hour))
+		return
+	})
+	if err != nil {
+		return toggl.TimeEntry{}, err
+	}
+
+	var found *toggl.TimeEntry
+	for i := range entries {
+		e := &entries[i]
+		if id != 0 && e.ID == id {
+			return *e, nil
+		}
+		if id == 0 && (found == nil || e.StartTime().After(found.StartTime())) {
+			found = e
+		}
+	}
+	if found == nil {
+		return toggl.TimeEntry{}, &Error{Code: InvalidParams, Message: "no time entry to continue"}
+	}
+	return *found, nil
+}
@@ -0,0 +1,105 @@
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jason0x43/go-toggl"
+)
+
+// newTestServer returns a server whose session talks to a local Toggl API
+// with a timer running, and a function returning the requests it received.
+func newTestServer(t *testing.T) (*Server, func() []string) {
+	t.Helper()
+	toggl.DisableLog()
+
+	var mu sync.Mutex
+	var requests []string
+	stopped := false
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, r.Method+" "+strings.TrimPrefix(r.URL.Path, "/api/v9"))
+
+		switch r.Method + " " + r.URL.Path {
+		case "GET /api/v9/me/time_entries/current":
+			if stopped {
+				w.Write([]byte("null"))
+				return
+			}
+			w.Write([]byte(`{"id":5,"workspace_id":1,"description":"Elsewhere","start":"2026-10-18T09:00:00Z","duration":-1}`))
+		case "PATCH /api/v9/workspaces/1/time_entries/5/stop":
+			stopped = true
+			w.Write([]byte(`{"id":5,"workspace_id":1,"description":"Elsewhere","start":"2026-10-18T09:00:00Z","stop":"2026-10-18T10:00:00Z","duration":3600}`))
+		case "POST /api/v9/workspaces/1/time_entries":
+			w.Write([]byte(`{"id":6,"workspace_id":1,"description":"Reviews","start":"2026-10-18T10:00:00Z","duration":-1}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(api.Close)
+
+	session := toggl.OpenSession("token")
+	session.Use(func(next toggl.RoundTripFunc) toggl.RoundTripFunc {
+		return func(req *toggl.Request) (*toggl.Response, error) {
+			req.API = strings.Replace(req.API, "https://api.track.toggl.com", api.URL, 1)
+			return next(req)
+		}
+	})
+
+	s := NewServer(&session)
+	s.MinRequestInterval = 0
+	return s, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string{}, requests...)
+	}
+}
+
+func TestStartWithStaleTimer(t *testing.T) {
+	// the server hasn't seen the timer that was started elsewhere
+	s, requests := newTestServer(t)
+
+	_, err := s.dispatch(nil, Request{Method: "start", Params: json.RawMessage(`{"description":"Reviews","workspace_id":1}`)})
+	var running *toggl.RunningTimerError
+	if !errors.As(err, &running) || running.Running.ID != 5 {
+		t.Fatalf("start returned %v, want a running timer error", err)
+	}
+
+	for _, r := range requests() {
+		if r != "GET /me/time_entries/current" {
+			t.Errorf("start sent %s", r)
+		}
+	}
+	if st := s.status(); !st.Running || st.Entry.ID != 5 {
+		t.Errorf("cached status is %+v, want the running timer", st)
+	}
+}
+
+func TestSwitch(t *testing.T) {
+	s, requests := newTestServer(t)
+
+	result, err := s.dispatch(nil, Request{Method: "switch", Params: json.RawMessage(`{"description":"Reviews","workspace_id":1}`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st := result.(Status); !st.Running || st.Entry.ID != 6 {
+		t.Errorf("switch returned %+v", st)
+	}
+
+	want := []string{
+		"GET /me/time_entries/current",
+		"PATCH /workspaces/1/time_entries/5/stop",
+		"GET /me/time_entries/current",
+		"POST /workspaces/1/time_entries",
+	}
+	got := requests()
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("switch sent\n\t%s\nwant\n\t%s", strings.Join(got, "\n\t"), strings.Join(want, "\n\t"))
+	}
+}
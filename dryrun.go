@@ -0,0 +1,115 @@
+package toggl
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// PlannedRequest is a mutating request recorded in dry-run mode instead of
+// being sent.
+type PlannedRequest struct {
+	Operation string          `json:"operation,omitempty"`
+	Method    string          `json:"method"`
+	URL       string          `json:"url"`
+	Body      json.RawMessage `json:"body,omitempty"`
+}
+
+// dryRun records the requests made by a session in dry-run mode. It's shared
+// by copies of the session.
+type dryRun struct {
+	mu       sync.Mutex
+	requests []PlannedRequest
+	nextID   int
+}
+
+var (
+	workspacePathPattern = regexp.MustCompile(`/workspaces/(\d+)(/|$)`)
+	resourceIDPattern    = regexp.MustCompile(`/(\d+)(/stop)?$`)
+)
+
+// SetDryRun enables or disables dry-run mode. In dry-run mode POST, PUT, PATCH
+// and DELETE requests aren't sent to Toggl. They're recorded, and a response is
+// made up from the request: created and updated resources are echoed back with
+// the IDs from the request path, or with negative IDs for new resources.
+// Requests that read data are still sent. Enabling dry-run mode clears the
+// recorded plan.
+func (session *Session) SetDryRun(enabled bool) {
+	if enabled {
+		session.dryRun = &dryRun{nextID: -1}
+	} else {
+		session.dryRun = nil
+	}
+}
+
+// IsDryRun returns true if the session is in dry-run mode.
+func (session *Session) IsDryRun() bool {
+	return session.dryRun != nil
+}
+
+// DryRunPlan returns the requests recorded in dry-run mode, in the order they
+// were made.
+func (session *Session) DryRunPlan() []PlannedRequest {
+	if session.dryRun == nil {
+		return nil
+	}
+	session.dryRun.mu.Lock()
+	defer session.dryRun.mu.Unlock()
+	return append([]PlannedRequest{}, session.dryRun.requests...)
+}
+
+// record records a mutating request and returns a made up response.
+func (d *dryRun) record(req *Request) (*Response, error) {
+	planned := PlannedRequest{Operation: req.Operation, Method: req.Method, URL: req.URL()}
+	if req.Body != nil {
+		body, err := json.Marshal(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		planned.Body = body
+	}
+
+	d.mu.Lock()
+	d.requests = append(d.requests, planned)
+	d.mu.Unlock()
+
+	dlog.Printf("Dry run: not sending %s %s", planned.Method, planned.URL)
+
+	if req.Method == "DELETE" {
+		return &Response{StatusCode: 200, Status: "200 OK"}, nil
+	}
+
+	resource := map[string]interface{}{}
+	if planned.Body != nil {
+		// bodies that aren't objects, such as lists, are only recorded
+		json.Unmarshal(planned.Body, &resource)
+	}
+
+	if m := resourceIDPattern.FindStringSubmatch(req.Path); m != nil {
+		id, _ := strconv.Atoi(m[1])
+		resource["id"] = id
+		if m[2] != "" {
+			now := time.Now().UTC().Format(time.RFC3339)
+			resource["stop"] = now
+		}
+	} else if req.Method == "POST" {
+		d.mu.Lock()
+		resource["id"] = d.nextID
+		d.nextID--
+		d.mu.Unlock()
+	}
+	if m := workspacePathPattern.FindStringSubmatch(req.Path); m != nil {
+		if _, ok := resource["workspace_id"]; !ok {
+			wid, _ := strconv.Atoi(m[1])
+			resource["workspace_id"] = wid
+		}
+	}
+
+	body, err := json.Marshal(resource)
+	if err != nil {
+		return nil, err
+	}
+	return &Response{StatusCode: 200, Status: "200 OK", Body: body}, nil
+}
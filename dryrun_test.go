@@ -0,0 +1,88 @@
+package toggl
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDryRunPlan(t *testing.T) {
+	entry := testEntry(1, "Reviews", testTime(9, 0), testTime(10, 0))
+	session, api := newTestSession(t, entry)
+	session.SetDryRun(true)
+
+	// reads are still sent
+	entries, err := session.GetTimeEntries(*testTime(0, 0), *testTime(23, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].ID != 1 {
+		t.Fatalf("unexpected entries %+v", entries)
+	}
+
+	entry.Description = "Code reviews"
+	updated, err := session.UpdateTimeEntry(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.ID != 1 || updated.Description != "Code reviews" {
+		t.Errorf("unexpected updated entry %+v", updated)
+	}
+
+	created, err := session.CreateTimeEntry(testEntry(0, "Deploy", testTime(10, 0), testTime(11, 0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.ID != -1 || created.Wid != 1 || created.Description != "Deploy" {
+		t.Errorf("unexpected created entry %+v", created)
+	}
+
+	if _, err := session.DeleteTimeEntry(entry); err != nil {
+		t.Fatal(err)
+	}
+
+	// nothing was changed
+	checkRequests(t, api.sent(), nil)
+	stored, _ := api.entry(1)
+	if stored.Description != "Reviews" {
+		t.Errorf("entry was changed: %+v", stored)
+	}
+	if ids := api.ids(); len(ids) != 1 {
+		t.Errorf("entries are %v", ids)
+	}
+
+	plan := session.DryRunPlan()
+	want := []struct {
+		operation, method, url string
+	}{
+		{"toggl.UpdateTimeEntry", "PUT", TogglAPI + "/workspaces/1/time_entries/1"},
+		{"toggl.CreateTimeEntry", "POST", TogglAPI + "/workspaces/1/time_entries"},
+		{"toggl.DeleteTimeEntry", "DELETE", TogglAPI + "/workspaces/1/time_entries/1"},
+	}
+	if len(plan) != len(want) {
+		t.Fatalf("got %d planned requests, want %d: %+v", len(plan), len(want), plan)
+	}
+	for i, w := range want {
+		p := plan[i]
+		if p.Operation != w.operation || p.Method != w.method || p.URL != w.url {
+			t.Errorf("request %d is %s %s %s, want %s %s %s", i, p.Operation, p.Method, p.URL, w.operation, w.method, w.url)
+		}
+	}
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(plan[0].Body, &body); err != nil || body["description"] != "Code reviews" {
+		t.Errorf("unexpected update body %s", plan[0].Body)
+	}
+	if plan[2].Body != nil {
+		t.Errorf("delete has body %s", plan[2].Body)
+	}
+
+	// turning dry-run mode off sends requests again
+	session.SetDryRun(false)
+	if session.DryRunPlan() != nil {
+		t.Error("plan wasn't cleared")
+	}
+	if _, err := session.DeleteTimeEntry(entry); err != nil {
+		t.Fatal(err)
+	}
+	checkRequests(t, api.sent(), []string{"DELETE /workspaces/1/time_entries/1"})
+}
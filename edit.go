@@ -0,0 +1,169 @@
+package toggl
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SplitTimeEntry splits a time entry into two at a given time. The original
+// entry is shortened to end at the split time, and a new entry with the same
+// description, project, task, tags and billable flag is created for the rest.
+// If the original entry is running, the new entry will be running. If the new
+// entry can't be created, the original entry is restored.
+func (session *Session) SplitTimeEntry(entry TimeEntry, at time.Time) (first, second TimeEntry, err error) {
+	dlog.Printf("Splitting timer %v at %v", entry, at)
+
+	if entry.Start == nil {
+		return first, second, fmt.Errorf("TimeEntry must have a start time")
+	}
+	if !at.After(*entry.Start) || (!entry.IsRunning() && !at.Before(entryStop(entry))) {
+		return first, second, fmt.Errorf("split time %v is outside of time entry", at)
+	}
+
+	rest := entry.Copy()
+	rest.ID = 0
+	if rest.IsRunning() {
+		rest.Duration = -1
+	} else {
+		stop := entryStop(entry)
+		rest.Stop = &stop
+	}
+	rest.SetStartTime(at, false)
+
+	head := entry.Copy()
+	head.Duration = 0
+	head.SetStopTime(at)
+
+	if first, err = session.UpdateTimeEntry(head); err != nil {
+		return first, second, fmt.Errorf("Error shortening time entry: %v", err)
+	}
+
+	if second, err = session.CreateTimeEntry(rest); err != nil {
+		err = fmt.Errorf("Error creating time entry: %v", err)
+		if _, rerr := session.UpdateTimeEntry(entry); rerr != nil {
+			err = fmt.Errorf("%v; original entry not restored: %v", err, rerr)
+		}
+		return TimeEntry{}, TimeEntry{}, err
+	}
+
+	return first, second, nil
+}
+
+// MergeTimeEntries merges time entries into a single entry that runs from the
+// earliest start to the latest stop. The entries must share a workspace,
+// project, task and billable flag; the merged entry has all of their tags and
+// their distinct descriptions joined by "; ". If one of the entries is running
+// it's kept, and the merged entry is running; otherwise the earliest entry is
+// kept. The other entries are deleted. If any step fails, entries that were
+// changed are restored, although deleted entries will be recreated with new
+// IDs.
+func (session *Session) MergeTimeEntries(entries ...TimeEntry) (TimeEntry, error) {
+	dlog.Printf("Merging timers %v", entries)
+
+	if len(entries) < 2 {
+		return TimeEntry{}, fmt.Errorf("at least two time entries are needed to merge")
+	}
+
+	sorted := make([]TimeEntry, len(entries))
+	copy(sorted, entries)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].StartTime().Before(sorted[j].StartTime())
+	})
+
+	keep := 0
+	running := false
+	var descriptions []string
+	seen := map[string]bool{}
+	var stop time.Time
+
+	for i, e := range sorted {
+		if e.Start == nil {
+			return TimeEntry{}, fmt.Errorf("time entry %d has no start time", e.ID)
+		}
+		if !sameTarget(sorted[0], e) {
+			return TimeEntry{}, fmt.Errorf("time entry %d has a different workspace, project, task or billable flag", e.ID)
+		}
+		if e.IsRunning() {
+			if running {
+				return TimeEntry{}, fmt.Errorf("only one running time entry can be merged")
+			}
+			running = true
+			keep = i
+		} else if s := entryStop(e); s.After(stop) {
+			stop = s
+		}
+		if e.Description != "" && !seen[e.Description] {
+			seen[e.Description] = true
+			descriptions = append(descriptions, e.Description)
+		}
+	}
+
+	merged := sorted[keep].Copy()
+	merged.Description = strings.Join(descriptions, "; ")
+	for _, e := range sorted {
+		for _, tag := range e.Tags {
+			merged.AddTag(tag)
+		}
+	}
+	start := *sorted[0].Start
+	merged.Start = &start
+	if running {
+		merged.Duration = -1
+	} else {
+		merged.SetStopTime(stop)
+	}
+
+	result, err := session.UpdateTimeEntry(merged)
+	if err != nil {
+		return TimeEntry{}, fmt.Errorf("Error updating time entry: %v", err)
+	}
+
+	var deleted []TimeEntry
+	for i, e := range sorted {
+		if i == keep {
+			continue
+		}
+		if _, err = session.DeleteTimeEntry(e); err != nil {
+			err = fmt.Errorf("Error deleting time entry %d: %v", e.ID, err)
+			break
+		}
+		deleted = append(deleted, e)
+	}
+	if err == nil {
+		return result, nil
+	}
+
+	// roll back
+	var failed []string
+	for _, e := range deleted {
+		if _, rerr := session.CreateTimeEntry(e); rerr != nil {
+			failed = append(failed, fmt.Sprintf("entry %d not recreated: %v", e.ID, rerr))
+		}
+	}
+	if _, rerr := session.UpdateTimeEntry(sorted[keep]); rerr != nil {
+		failed = append(failed, fmt.Sprintf("entry %d not restored: %v", sorted[keep].ID, rerr))
+	}
+	if len(failed) > 0 {
+		err = fmt.Errorf("%v; %s", err, strings.Join(failed, "; "))
+	}
+	return TimeEntry{}, err
+}
+
+// entryStop returns the time a stopped entry ends.
+func entryStop(e TimeEntry) time.Time {
+	if e.Stop != nil {
+		return *e.Stop
+	}
+	return e.Start.Add(time.Duration(e.Duration) * time.Second)
+}
+
+// sameTarget returns true if two entries are tracked against the same
+// workspace, project and task with the same billable flag.
+func sameTarget(a, b TimeEntry) bool {
+	same := func(x, y *int) bool {
+		return (x == nil && y == nil) || (x != nil && y != nil && *x == *y)
+	}
+	return a.Wid == b.Wid && a.Billable == b.Billable && same(a.Pid, b.Pid) && same(a.Tid, b.Tid)
+}
@@ -0,0 +1,149 @@
+package toggl
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestSplitTimeEntry(t *testing.T) {
+	entry := testEntry(1, "Reviews", testTime(9, 0), testTime(11, 0))
+	session, api := newTestSession(t, entry)
+
+	first, second, err := session.SplitTimeEntry(entry, *testTime(10, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.ID != 1 || !first.Stop.Equal(*testTime(10, 0)) || first.Duration != 3600 {
+		t.Errorf("unexpected first entry %+v", first)
+	}
+	if second.ID != 100 || !second.Start.Equal(*testTime(10, 0)) || second.Duration != 3600 {
+		t.Errorf("unexpected second entry %+v", second)
+	}
+	if second.Description != "Reviews" || !second.Billable || second.Pid == nil || *second.Pid != 7 || !second.HasTag("client") {
+		t.Errorf("second entry lost metadata: %+v", second)
+	}
+
+	checkRequests(t, api.sent(), []string{
+		"PUT /workspaces/1/time_entries/1",
+		"POST /workspaces/1/time_entries",
+	})
+}
+
+func TestSplitTimeEntryRollback(t *testing.T) {
+	entry := testEntry(1, "Reviews", testTime(9, 0), testTime(11, 0))
+	session, api := newTestSession(t, entry)
+	api.fail("POST /workspaces/1/time_entries", http.StatusInternalServerError)
+
+	if _, _, err := session.SplitTimeEntry(entry, *testTime(10, 0)); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	checkRequests(t, api.sent(), []string{
+		"PUT /workspaces/1/time_entries/1",
+		"POST /workspaces/1/time_entries",
+		"PUT /workspaces/1/time_entries/1",
+	})
+	restored, _ := api.entry(1)
+	if !restored.Stop.Equal(*testTime(11, 0)) || restored.Duration != 7200 {
+		t.Errorf("entry wasn't restored: %+v", restored)
+	}
+	if ids := api.ids(); len(ids) != 1 {
+		t.Errorf("unexpected entries %v", ids)
+	}
+}
+
+func TestMergeTimeEntries(t *testing.T) {
+	a := testEntry(1, "Reviews", testTime(9, 0), testTime(10, 0))
+	b := testEntry(2, "Deploy", testTime(10, 0), testTime(11, 0))
+	b.Tags = []string{"ops"}
+	session, api := newTestSession(t, a, b)
+
+	merged, err := session.MergeTimeEntries(b, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if merged.ID != 1 || !merged.Start.Equal(*testTime(9, 0)) || !merged.Stop.Equal(*testTime(11, 0)) {
+		t.Errorf("unexpected merged entry %+v", merged)
+	}
+	if merged.Description != "Reviews; Deploy" || !merged.HasTag("client") || !merged.HasTag("ops") {
+		t.Errorf("unexpected merged metadata %+v", merged)
+	}
+
+	checkRequests(t, api.sent(), []string{
+		"PUT /workspaces/1/time_entries/1",
+		"DELETE /workspaces/1/time_entries/2",
+	})
+}
+
+func TestMergeTimeEntriesRollback(t *testing.T) {
+	entries := []TimeEntry{
+		testEntry(1, "Reviews", testTime(9, 0), testTime(10, 0)),
+		testEntry(2, "Reviews", testTime(10, 0), testTime(11, 0)),
+		testEntry(3, "Reviews", testTime(11, 0), testTime(12, 0)),
+	}
+
+	tests := []struct {
+		name     string
+		failures []string
+		errors   []string
+		ids      []int
+	}{
+		{
+			name:     "restored",
+			failures: []string{"DELETE /workspaces/1/time_entries/3"},
+			errors:   []string{"Error deleting time entry 3"},
+			ids:      []int{1, 3, 100},
+		},
+		{
+			name: "not recreated",
+			failures: []string{
+				"DELETE /workspaces/1/time_entries/3",
+				"POST /workspaces/1/time_entries",
+			},
+			errors: []string{"Error deleting time entry 3", "entry 2 not recreated"},
+			ids:    []int{1, 3},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			session, api := newTestSession(t, entries...)
+			for _, request := range test.failures {
+				api.fail(request, http.StatusInternalServerError)
+			}
+
+			_, err := session.MergeTimeEntries(entries...)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			for _, msg := range test.errors {
+				if !strings.Contains(err.Error(), msg) {
+					t.Errorf("error %q doesn't mention %q", err, msg)
+				}
+			}
+
+			checkRequests(t, api.sent(), []string{
+				"PUT /workspaces/1/time_entries/1",
+				"DELETE /workspaces/1/time_entries/2",
+				"DELETE /workspaces/1/time_entries/3",
+				"POST /workspaces/1/time_entries",
+				"PUT /workspaces/1/time_entries/1",
+			})
+
+			// the kept entry is restored, and the deleted one is recreated
+			// with a new ID
+			kept, _ := api.entry(1)
+			if !kept.Stop.Equal(*testTime(10, 0)) || kept.Duration != 3600 {
+				t.Errorf("kept entry wasn't restored: %+v", kept)
+			}
+			if ids := api.ids(); fmt.Sprint(ids) != fmt.Sprint(test.ids) {
+				t.Errorf("entries are %v, want %v", ids, test.ids)
+			}
+			if recreated, ok := api.entry(100); ok && (!recreated.Start.Equal(*testTime(10, 0)) || recreated.Description != "Reviews") {
+				t.Errorf("unexpected recreated entry %+v", recreated)
+			}
+		})
+	}
+}
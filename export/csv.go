@@ -0,0 +1,85 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// CSVHeader returns the column names used by Toggl's own CSV export. The
+// amount column names the currency, if one is given.
+func CSVHeader(currency string) []string {
+	amount := "Amount"
+	if currency != "" {
+		amount += " (" + currency + ")"
+	}
+	return []string{
+		"User",
+		"Email",
+		"Client",
+		"Project",
+		"Task",
+		"Description",
+		"Billable",
+		"Start date",
+		"Start time",
+		"End date",
+		"End time",
+		"Duration",
+		"Tags",
+		amount,
+	}
+}
+
+// WriteCSV writes rows to w in Toggl's CSV export format, including a header
+// line. Amounts are in the given currency, which may be empty.
+func WriteCSV(w io.Writer, rows []Row, currency string) error {
+	cw := csv.NewWriter(w)
+
+	if err := cw.Write(CSVHeader(currency)); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		billable := "No"
+		if row.Billable {
+			billable = "Yes"
+		}
+
+		amount := ""
+		if row.Amount != 0 {
+			amount = fmt.Sprintf("%.2f", row.Amount)
+		}
+
+		record := []string{
+			row.User,
+			row.Email,
+			row.Client,
+			row.Project,
+			row.Task,
+			row.Description,
+			billable,
+			row.Start.Format("2006-01-02"),
+			row.Start.Format("15:04:05"),
+			row.End.Format("2006-01-02"),
+			row.End.Format("15:04:05"),
+			FormatDuration(row.Duration),
+			strings.Join(row.Tags, ", "),
+			amount,
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// FormatDuration formats a duration as HH:MM:SS.
+func FormatDuration(d time.Duration) string {
+	seconds := int64(d / time.Second)
+	return fmt.Sprintf("%02d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+}
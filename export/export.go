@@ -0,0 +1,155 @@
+/*
+Package export converts Toggl time entries into formats understood by other
+tools.
+
+Both TimeEntry values and DetailedTimeEntry values from detailed reports are
+first normalized into Rows, which carry resolved client, project and task names.
+*/
+package export
+
+import (
+	"time"
+
+	"github.com/jason0x43/go-toggl"
+)
+
+// Row is a time entry with its related names resolved.
+type Row struct {
+	ID          int
+	User        string
+	Email       string
+	Client      string
+	Project     string
+	Task        string
+	Description string
+	Billable    bool
+	Start       time.Time
+	End         time.Time
+	Duration    time.Duration
+	Tags        []string
+	Amount      float64
+}
+
+// Options control how rows are created.
+type Options struct {
+	// User and Email are used for entries that don't name a user.
+	User  string
+	Email string
+
+	// Location is the time zone that start and end times are converted to. If
+	// nil, times are left as they are.
+	Location *time.Location
+
+	// HourlyRate, if set, is used to compute the amount of billable entries.
+	// It is given the entry's project ID, or 0 if it has none, and the ID of
+	// the user who tracked it, or 0 if it's unknown.
+	HourlyRate func(pid, uid int) float64
+}
+
+// TimeEntryRows converts time entries into rows, using index to resolve
+// project, client and task names. Running entries are skipped.
+func TimeEntryRows(entries []toggl.TimeEntry, index toggl.Index, opts Options) []Row {
+	var rows []Row
+	for _, e := range entries {
+		if e.IsRunning() || e.Start == nil {
+			continue
+		}
+
+		row := Row{
+			ID:          e.ID,
+			User:        opts.User,
+			Email:       opts.Email,
+			Client:      index.ClientName(e.Pid),
+			Project:     index.ProjectName(e.Pid),
+			Task:        index.TaskName(e.Tid),
+			Description: e.Description,
+			Billable:    e.Billable,
+			Start:       opts.localize(*e.Start),
+			Duration:    time.Duration(e.Duration) * time.Second,
+			Tags:        e.Tags,
+		}
+		if e.Stop != nil {
+			row.End = opts.localize(*e.Stop)
+		} else {
+			row.End = row.Start.Add(row.Duration)
+		}
+
+		pid := 0
+		if e.Pid != nil {
+			pid = *e.Pid
+		}
+		row.Amount = opts.amount(pid, e.Uid, row)
+
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// DetailedTimeEntryRows converts detailed report entries into rows. Names
+// already present in the entries are used as-is; missing ones are resolved
+// using index.
+func DetailedTimeEntryRows(entries []toggl.DetailedTimeEntry, index toggl.Index, opts Options) []Row {
+	var rows []Row
+	for _, e := range entries {
+		if e.Start == nil {
+			continue
+		}
+
+		pid := &e.Pid
+		if e.Pid == 0 {
+			pid = nil
+		}
+		tid := &e.Tid
+		if e.Tid == 0 {
+			tid = nil
+		}
+
+		row := Row{
+			ID:          e.ID,
+			User:        firstNonEmpty(e.User, opts.User),
+			Client:      firstNonEmpty(e.Client, index.ClientName(pid)),
+			Project:     firstNonEmpty(e.Project, index.ProjectName(pid)),
+			Task:        firstNonEmpty(e.Task, index.TaskName(tid)),
+			Description: e.Description,
+			Billable:    e.Billable,
+			Start:       opts.localize(*e.Start),
+			Duration:    time.Duration(e.Duration) * time.Millisecond,
+			Tags:        e.Tags,
+		}
+		if e.End != nil {
+			row.End = opts.localize(*e.End)
+		} else {
+			row.End = row.Start.Add(row.Duration)
+		}
+		if e.User == "" {
+			row.Email = opts.Email
+		}
+		row.Amount = opts.amount(e.Pid, e.Uid, row)
+
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func (opts Options) localize(t time.Time) time.Time {
+	if opts.Location != nil {
+		return t.In(opts.Location)
+	}
+	return t
+}
+
+func (opts Options) amount(pid, uid int, row Row) float64 {
+	if opts.HourlyRate == nil || !row.Billable {
+		return 0
+	}
+	return opts.HourlyRate(pid, uid) * row.Duration.Hours()
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
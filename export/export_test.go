@@ -0,0 +1,36 @@
+package export
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jason0x43/go-toggl"
+)
+
+func TestDetailedTimeEntryRowsAmount(t *testing.T) {
+	start := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	entries := []toggl.DetailedTimeEntry{
+		{ID: 1, Pid: 10, Uid: 1, User: "Ann", Start: &start, Duration: 3600000, Billable: true},
+		{ID: 2, Pid: 10, Uid: 2, User: "Bob", Start: &start, Duration: 1800000, Billable: true},
+		{ID: 3, Pid: 10, Uid: 2, User: "Bob", Start: &start, Duration: 3600000},
+	}
+	opts := Options{
+		HourlyRate: func(pid, uid int) float64 {
+			if pid != 10 {
+				t.Errorf("rate requested for project %d", pid)
+			}
+			return float64(uid) * 100
+		},
+	}
+
+	rows := DetailedTimeEntryRows(entries, toggl.Index{}, opts)
+	want := []float64{100, 100, 0}
+	if len(rows) != len(want) {
+		t.Fatalf("got %d rows, want %d", len(rows), len(want))
+	}
+	for i, row := range rows {
+		if row.Amount != want[i] {
+			t.Errorf("row %d has amount %v, want %v", row.ID, row.Amount, want[i])
+		}
+	}
+}
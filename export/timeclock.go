@@ -0,0 +1,62 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// UnassignedAccount is the timeclock account used for rows with no client,
+// project or task.
+const UnassignedAccount = "unassigned"
+
+const timeclockLayout = "2006/01/02 15:04:05"
+
+// TimeclockAccount returns the ledger account name for a row, made up of its
+// client, project and task names separated by colons.
+func TimeclockAccount(row Row) string {
+	var parts []string
+	for _, name := range []string{row.Client, row.Project, row.Task} {
+		// colons separate account components, and two spaces end the account
+		name = strings.Join(strings.Fields(strings.ReplaceAll(name, ":", "-")), " ")
+		if name != "" {
+			parts = append(parts, name)
+		}
+	}
+	if len(parts) == 0 {
+		return UnassignedAccount
+	}
+	return strings.Join(parts, ":")
+}
+
+// WriteTimeclock writes rows in the timeclock format read by ledger and
+// hledger, as a clock-in line and a clock-out line per row ordered by start
+// time. A row's description follows its account, and its tags are preserved in
+// a comment line before the clock-in.
+func WriteTimeclock(w io.Writer, rows []Row) error {
+	sorted := make([]Row, len(rows))
+	copy(sorted, rows)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Start.Before(sorted[j].Start)
+	})
+
+	for _, row := range sorted {
+		if len(row.Tags) > 0 {
+			if _, err := fmt.Fprintf(w, "; tags: %s\n", strings.Join(row.Tags, ", ")); err != nil {
+				return err
+			}
+		}
+
+		in := fmt.Sprintf("i %s %s", row.Start.Format(timeclockLayout), TimeclockAccount(row))
+		if desc := strings.Join(strings.Fields(row.Description), " "); desc != "" {
+			in += "  " + desc
+		}
+		_, err := fmt.Fprintf(w, "%s\no %s\n", in, row.End.Format(timeclockLayout))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,204 @@
+package exporter
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jason0x43/go-toggl"
+)
+
+// latencyBuckets are the upper bounds of the request latency histogram, in
+// seconds.
+var latencyBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// APIMetrics counts the requests a session makes to Toggl. Its Middleware
+// method is added to a session to observe its requests:
+//
+//	metrics := exporter.NewAPIMetrics()
+//	session.Use(metrics.Middleware)
+type APIMetrics struct {
+	mu          sync.Mutex
+	requests    map[requestKey]int
+	latencies   map[string]*histogram
+	errors      int
+	rateLimited int
+}
+
+type requestKey struct {
+	method string
+	api    string
+	status string
+}
+
+type histogram struct {
+	counts []int
+	count  int
+	sum    float64
+}
+
+// NewAPIMetrics returns an empty set of API metrics.
+func NewAPIMetrics() *APIMetrics {
+	return &APIMetrics{
+		requests:  map[requestKey]int{},
+		latencies: map[string]*histogram{},
+	}
+}
+
+// Middleware is session middleware that records every request.
+func (m *APIMetrics) Middleware(next toggl.RoundTripFunc) toggl.RoundTripFunc {
+	return func(req *toggl.Request) (*toggl.Response, error) {
+		start := time.Now()
+		resp, err := next(req)
+
+		statusCode := 0
+		if resp != nil {
+			statusCode = resp.StatusCode
+		}
+		m.observe(req.Method, req.URL(), statusCode, time.Since(start), err)
+		return resp, err
+	}
+}
+
+// observe records a request. statusCode is 0 if no response was received.
+func (m *APIMetrics) observe(method, rawURL string, statusCode int, d time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	status := "error"
+	if statusCode != 0 {
+		status = strconv.Itoa(statusCode)
+	}
+	m.requests[requestKey{method, apiName(rawURL), status}]++
+	if statusCode == 0 && err != nil {
+		m.errors++
+	}
+	if statusCode == 429 {
+		m.rateLimited++
+	}
+
+	h := m.latencies[method]
+	if h == nil {
+		h = &histogram{counts: make([]int, len(latencyBuckets))}
+		m.latencies[method] = h
+	}
+	seconds := d.Seconds()
+	for i, b := range latencyBuckets {
+		if seconds <= b {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += seconds
+}
+
+// WriteTo writes the metrics in the Prometheus text format.
+func (m *APIMetrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	mw := &metricWriter{w: w}
+
+	mw.header("toggl_api_requests_total", "counter", "Requests made to the Toggl API.")
+	var keys []requestKey
+	for k := range m.requests {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j])
+	})
+	for _, k := range keys {
+		mw.sample("toggl_api_requests_total", float64(m.requests[k]), "method", k.method, "api", k.api, "status", k.status)
+	}
+
+	mw.header("toggl_api_request_duration_seconds", "histogram", "Latency of requests to the Toggl API.")
+	var methods []string
+	for method := range m.latencies {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	for _, method := range methods {
+		h := m.latencies[method]
+		for i, b := range latencyBuckets {
+			mw.sample("toggl_api_request_duration_seconds_bucket", float64(h.counts[i]), "method", method, "le", formatFloat(b))
+		}
+		mw.sample("toggl_api_request_duration_seconds_bucket", float64(h.count), "method", method, "le", "+Inf")
+		mw.sample("toggl_api_request_duration_seconds_sum", h.sum, "method", method)
+		mw.sample("toggl_api_request_duration_seconds_count", float64(h.count), "method", method)
+	}
+
+	mw.header("toggl_api_errors_total", "counter", "Requests to the Toggl API that got no response.")
+	mw.sample("toggl_api_errors_total", float64(m.errors))
+
+	mw.header("toggl_api_rate_limited_total", "counter", "Requests to the Toggl API rejected by rate limiting.")
+	mw.sample("toggl_api_rate_limited_total", float64(m.rateLimited))
+
+	return mw.n, mw.err
+}
+
+// apiName returns a short name for the Toggl API a URL belongs to, such as
+// "api/v9" or "reports/api/v2", to keep label cardinality low.
+func apiName(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "unknown"
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	for i, p := range parts {
+		if len(p) > 1 && p[0] == 'v' {
+			if _, err := strconv.Atoi(p[1:]); err == nil {
+				return strings.Join(parts[:i+1], "/")
+			}
+		}
+	}
+	return "unknown"
+}
+
+// metricWriter writes the Prometheus text format, remembering the first
+// error.
+type metricWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (mw *metricWriter) printf(format string, args ...interface{}) {
+	if mw.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(mw.w, format, args...)
+	mw.n += int64(n)
+	mw.err = err
+}
+
+func (mw *metricWriter) header(name, kind, help string) {
+	mw.printf("# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// sample writes a sample with labels given as name, value pairs.
+func (mw *metricWriter) sample(name string, value float64, labels ...string) {
+	var b strings.Builder
+	b.WriteString(name)
+	if len(labels) > 0 {
+		b.WriteString("{")
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				b.WriteString(",")
+			}
+			b.WriteString(labels[i] + `="` + labelEscaper.Replace(labels[i+1]) + `"`)
+		}
+		b.WriteString("}")
+	}
+	mw.printf("%s %s\n", b.String(), formatFloat(value))
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,282 @@
+/*
+Package exporter serves Toggl data as Prometheus metrics.
+
+An Exporter is an http.Handler that responds with tracked time for today and
+the current week, grouped by user, client and project, along with the share of
+billable time and the state of the session user's running timer. By default
+only the session user's own time is included. If Workspace is set, the time of
+every member of the workspace is loaded from its detailed report instead;
+reports only include stopped entries, and only show other members' time to
+workspace admins. Tracked time is labelled with the IDs of its user, client
+and project as well as their names, which needn't be unique.
+
+If the session uses an APIMetrics' middleware, the exporter also reports on
+the requests made to Toggl:
+
+	metrics := exporter.NewAPIMetrics()
+	session.Use(metrics.Middleware)
+	http.Handle("/metrics", exporter.New(&session, metrics))
+
+Toggl data is cached for MaxAge, so frequent scrapes don't exceed Toggl's rate
+limits.
+*/
+package exporter
+
+import (
+	"bytes"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jason0x43/go-toggl"
+	"github.com/jason0x43/go-toggl/aggregate"
+	"github.com/jason0x43/go-toggl/query"
+)
+
+// Exporter serves metrics about a session's tracked time.
+type Exporter struct {
+	Session *toggl.Session
+
+	// API, if set, is included in the metrics.
+	API *APIMetrics
+
+	// MaxAge is how long Toggl data is reused between scrapes.
+	MaxAge time.Duration
+
+	// Workspace, if set, is the workspace whose members' time is reported.
+	Workspace int
+
+	mu          sync.Mutex
+	snapshot    snapshot
+	scrapeError int
+}
+
+// snapshot is the Toggl data metrics are computed from.
+type snapshot struct {
+	account toggl.Account
+	entries []toggl.TimeEntry
+	users   map[int]string
+	current toggl.TimeEntry
+	fetched time.Time
+}
+
+// New returns an exporter for a session that caches Toggl data for a minute.
+func New(session *toggl.Session, api *APIMetrics) *Exporter {
+	return &Exporter{Session: session, API: api, MaxAge: time.Minute}
+}
+
+// ServeHTTP writes the metrics in the Prometheus text format.
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var buf bytes.Buffer
+	mw := &metricWriter{w: &buf}
+
+	snap, err := e.fetch()
+	e.mu.Lock()
+	if err != nil {
+		e.scrapeError++
+	}
+	scrapeErrors := e.scrapeError
+	e.mu.Unlock()
+
+	mw.header("toggl_scrape_errors_total", "counter", "Failed attempts to load data from Toggl.")
+	mw.sample("toggl_scrape_errors_total", float64(scrapeErrors))
+
+	if !snap.fetched.IsZero() {
+		writeTracked(mw, snap, time.Now())
+	}
+	if mw.err == nil && e.API != nil {
+		_, mw.err = e.API.WriteTo(&buf)
+	}
+
+	if mw.err != nil {
+		http.Error(w, mw.err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.Write(buf.Bytes())
+}
+
+// fetch returns the cached snapshot, reloading it if it's older than MaxAge.
+// If reloading fails the stale snapshot is returned with the error.
+func (e *Exporter) fetch() (snapshot, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if time.Since(e.snapshot.fetched) < e.MaxAge {
+		return e.snapshot, nil
+	}
+
+	account, err := e.Session.GetAccount()
+	if err != nil {
+		return e.snapshot, err
+	}
+
+	now := time.Now()
+	weekStart := startOfWeek(now, account)
+	users := map[int]string{account.ID: account.Fullname}
+
+	var entries []toggl.TimeEntry
+	if e.Workspace != 0 {
+		detailed, err := query.New().
+			Between(weekStart, now.Add(time.Hour)).
+			FetchDetailed(e.Session, e.Workspace)
+		if err != nil {
+			return e.snapshot, err
+		}
+		for _, d := range detailed {
+			if d.User != "" {
+				users[d.Uid] = d.User
+			}
+			entries = append(entries, fromDetailed(d, e.Workspace))
+		}
+	} else {
+		if entries, err = e.Session.GetTimeEntries(weekStart, now.Add(time.Hour)); err != nil {
+			return e.snapshot, err
+		}
+	}
+
+	current, err := e.Session.GetCurrentTimeEntry()
+	if err != nil {
+		return e.snapshot, err
+	}
+
+	e.snapshot = snapshot{account: account, entries: entries, users: users, current: current, fetched: now}
+	return e.snapshot, nil
+}
+
+// fromDetailed converts an entry from a detailed report into a time entry.
+func fromDetailed(d toggl.DetailedTimeEntry, wid int) toggl.TimeEntry {
+	entry := toggl.TimeEntry{
+		Wid:         wid,
+		ID:          d.ID,
+		Uid:         d.Uid,
+		Description: d.Description,
+		Start:       d.Start,
+		Stop:        d.End,
+		Tags:        d.Tags,
+		Duration:    d.Duration / 1000,
+		Billable:    d.Billable,
+	}
+	if d.Pid != 0 {
+		pid := d.Pid
+		entry.Pid = &pid
+	}
+	if d.Tid != 0 {
+		tid := d.Tid
+		entry.Tid = &tid
+	}
+	return entry
+}
+
+// userName returns the label used for a user: their name, or their ID if
+// it's unknown.
+func (snap snapshot) userName(uid int) string {
+	if name := snap.users[uid]; name != "" {
+		return name
+	}
+	return strconv.Itoa(uid)
+}
+
+// writeTracked writes the tracked time metrics for a snapshot.
+func writeTracked(mw *metricWriter, snap snapshot, now time.Time) {
+	today := now.In(location(snap.account)).Format("2006-01-02")
+
+	periods := []struct {
+		name string
+		dim  aggregate.Dimension
+		key  string
+	}{
+		{"today", aggregate.Day, today},
+		{"week", aggregate.Week, startOfWeek(now, snap.account).Format("2006-01-02")},
+	}
+
+	byUser := map[int][]toggl.TimeEntry{}
+	for _, entry := range snap.entries {
+		byUser[entry.Uid] = append(byUser[entry.Uid], entry)
+	}
+	var users []int
+	for uid := range byUser {
+		users = append(users, uid)
+	}
+	sort.Ints(users)
+
+	sum := func(entries []toggl.TimeEntry, dims ...aggregate.Dimension) *aggregate.Node {
+		return aggregate.Aggregate(entries).ForAccount(snap.account).At(now).By(dims...).Sum()
+	}
+
+	mw.header("toggl_tracked_seconds", "gauge", "Time tracked in the period, by user, client and project.")
+	for _, period := range periods {
+		for _, uid := range users {
+			node := sum(byUser[uid], period.dim, aggregate.Client, aggregate.Project).Child(period.key)
+			if node == nil {
+				continue
+			}
+			for _, client := range node.Children {
+				for _, project := range client.Children {
+					mw.sample(
+						"toggl_tracked_seconds",
+						project.Duration.Seconds(),
+						"period", period.name,
+						"user_id", strconv.Itoa(uid),
+						"user", snap.userName(uid),
+						"client_id", client.Key,
+						"client", client.Label,
+						"project_id", project.Key,
+						"project", project.Label,
+					)
+				}
+			}
+		}
+	}
+
+	var billable []toggl.TimeEntry
+	for _, entry := range snap.entries {
+		if entry.Billable {
+			billable = append(billable, entry)
+		}
+	}
+
+	mw.header("toggl_billable_ratio", "gauge", "Share of the time tracked in the period that's billable.")
+	for _, period := range periods {
+		total := sum(snap.entries, period.dim).Child(period.key)
+		ratio := 0.0
+		if total != nil && total.Duration > 0 {
+			if b := sum(billable, period.dim).Child(period.key); b != nil {
+				ratio = b.Duration.Seconds() / total.Duration.Seconds()
+			}
+		}
+		mw.sample("toggl_billable_ratio", ratio, "period", period.name)
+	}
+
+	running, elapsed := 0.0, 0.0
+	if snap.current.ID != 0 {
+		running = 1
+		elapsed = now.Sub(snap.current.StartTime()).Seconds()
+	}
+	mw.header("toggl_timer_running", "gauge", "Whether a timer is running.")
+	mw.sample("toggl_timer_running", running)
+	mw.header("toggl_timer_elapsed_seconds", "gauge", "How long the running timer has been running.")
+	mw.sample("toggl_timer_elapsed_seconds", elapsed)
+}
+
+// startOfWeek returns midnight on the first day of the week containing t, in
+// the account's time zone.
+func startOfWeek(t time.Time, account toggl.Account) time.Time {
+	loc := location(account)
+	t = t.In(loc)
+	offset := (int(t.Weekday()) - account.BeginningOfWeek%7 + 7) % 7
+	y, m, d := t.Date()
+	return time.Date(y, m, d-offset, 0, 0, 0, 0, loc)
+}
+
+// location returns the account's time zone, or the local one.
+func location(account toggl.Account) *time.Location {
+	if account.Timezone != "" {
+		if loc, err := time.LoadLocation(account.Timezone); err == nil {
+			return loc
+		}
+	}
+	return time.Local
+}
@@ -0,0 +1,76 @@
+package exporter
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jason0x43/go-toggl"
+)
+
+func TestWriteTrackedDuplicateNames(t *testing.T) {
+	acme, globex := 1, 2
+	web, otherWeb := 10, 20
+	account := toggl.Account{
+		ID:       100,
+		Fullname: "Ann",
+		Timezone: "UTC",
+		Clients: []toggl.Client{
+			{ID: acme, Wid: 1, Name: "Acme"},
+			{ID: globex, Wid: 1, Name: "Acme"},
+		},
+		Projects: []toggl.Project{
+			{ID: web, Wid: 1, Cid: &acme, Name: "Web"},
+			{ID: otherWeb, Wid: 1, Cid: &globex, Name: "Web"},
+		},
+	}
+
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	entry := func(id, uid int, pid *int) toggl.TimeEntry {
+		start := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+		stop := start.Add(time.Hour)
+		return toggl.TimeEntry{ID: id, Wid: 1, Uid: uid, Pid: pid, Start: &start, Stop: &stop, Duration: 3600}
+	}
+	snap := snapshot{
+		account: account,
+		entries: []toggl.TimeEntry{
+			entry(1, 100, &web),
+			entry(2, 100, &otherWeb),
+			entry(3, 200, &web),
+			entry(4, 300, nil),
+		},
+		users: map[int]string{100: "Ann", 200: "Ann"},
+	}
+
+	var buf bytes.Buffer
+	mw := &metricWriter{w: &buf}
+	writeTracked(mw, snap, now)
+	if mw.err != nil {
+		t.Fatal(mw.err)
+	}
+
+	var series []string
+	seen := map[string]bool{}
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if !strings.HasPrefix(line, `toggl_tracked_seconds{period="today"`) {
+			continue
+		}
+		labels := line[:strings.LastIndex(line, " ")]
+		if seen[labels] {
+			t.Errorf("duplicate series %s", labels)
+		}
+		seen[labels] = true
+		series = append(series, line)
+	}
+
+	want := []string{
+		`toggl_tracked_seconds{period="today",user_id="100",user="Ann",client_id="1",client="Acme",project_id="10",project="Web"} 3600`,
+		`toggl_tracked_seconds{period="today",user_id="100",user="Ann",client_id="2",client="Acme",project_id="20",project="Web"} 3600`,
+		`toggl_tracked_seconds{period="today",user_id="200",user="Ann",client_id="1",client="Acme",project_id="10",project="Web"} 3600`,
+		`toggl_tracked_seconds{period="today",user_id="300",user="300",client_id="",client="(no client)",project_id="",project="(no project)"} 3600`,
+	}
+	if strings.Join(series, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got series\n%s\nwant\n%s", strings.Join(series, "\n"), strings.Join(want, "\n"))
+	}
+}
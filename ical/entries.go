@@ -0,0 +1,206 @@
+package ical
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jason0x43/go-toggl"
+	"github.com/jason0x43/go-toggl/importer"
+)
+
+// X- properties used to carry Toggl metadata
+const (
+	PropProjectID   = "X-TOGGL-PROJECT-ID"
+	PropProject     = "X-TOGGL-PROJECT"
+	PropClient      = "X-TOGGL-CLIENT"
+	PropWorkspaceID = "X-TOGGL-WORKSPACE-ID"
+	PropBillable    = "X-TOGGL-BILLABLE"
+)
+
+// UID returns the stable event UID for the time entry with a given ID.
+func UID(entryID int) string {
+	return fmt.Sprintf("time-entry-%d@track.toggl.com", entryID)
+}
+
+// FromTimeEntries converts time entries into events, using index to resolve
+// project and client names. Running entries are skipped.
+func FromTimeEntries(entries []toggl.TimeEntry, index toggl.Index) []Event {
+	var events []Event
+	for _, e := range entries {
+		if e.IsRunning() || e.Start == nil {
+			continue
+		}
+
+		event := Event{
+			UID:        UID(e.ID),
+			Summary:    e.Description,
+			Categories: e.Tags,
+			Start:      *e.Start,
+			End:        e.StopTime(),
+			Extra: map[string]string{
+				PropWorkspaceID: strconv.Itoa(e.Wid),
+				PropBillable:    strconv.FormatBool(e.Billable),
+			},
+		}
+		if e.Stop == nil {
+			event.End = event.Start.Add(time.Duration(e.Duration) * time.Second)
+		}
+
+		project := index.ProjectName(e.Pid)
+		client := index.ClientName(e.Pid)
+		if project != "" {
+			event.Extra[PropProjectID] = strconv.Itoa(*e.Pid)
+			event.Extra[PropProject] = project
+			event.Location = project
+		}
+		if client != "" {
+			event.Extra[PropClient] = client
+			event.Location = client + " / " + project
+		}
+
+		events = append(events, event)
+	}
+	return events
+}
+
+// Rule maps calendar events to Toggl metadata. A rule matches an event when
+// its pattern matches the event's Field, which may be "summary" (the default),
+// "description", "location" or "category". An empty pattern matches every
+// event, so a final catch-all rule with Skip set can be used to drop events no
+// other rule matched.
+type Rule struct {
+	Field    string   `json:"field"`
+	Pattern  string   `json:"pattern"`
+	Project  string   `json:"project"`
+	Client   string   `json:"client"`
+	Task     string   `json:"task"`
+	Tags     []string `json:"tags"`
+	Billable bool     `json:"billable"`
+	Skip     bool     `json:"skip"`
+
+	re *regexp.Regexp
+}
+
+// RuleSet is an ordered list of rules. The first matching rule is used.
+type RuleSet []Rule
+
+// ReadRules reads a JSON array of rules and compiles their patterns.
+func ReadRules(r io.Reader) (RuleSet, error) {
+	var rules RuleSet
+	if err := json.NewDecoder(r).Decode(&rules); err != nil {
+		return nil, fmt.Errorf("Error decoding rules: %v", err)
+	}
+	return rules, rules.Compile()
+}
+
+// Compile compiles the rules' patterns. It must be called before a rule set
+// that wasn't created by ReadRules is used.
+func (rs RuleSet) Compile() error {
+	for i := range rs {
+		re, err := regexp.Compile(rs[i].Pattern)
+		if err != nil {
+			return fmt.Errorf("rule %d: %v", i+1, err)
+		}
+		rs[i].re = re
+	}
+	return nil
+}
+
+// Match returns the first rule that matches an event.
+func (rs RuleSet) Match(event Event) (Rule, bool) {
+	for _, rule := range rs {
+		if rule.re == nil {
+			continue
+		}
+
+		var values []string
+		switch rule.Field {
+		case "", "summary":
+			values = []string{event.Summary}
+		case "description":
+			values = []string{event.Description}
+		case "location":
+			values = []string{event.Location}
+		case "category":
+			values = event.Categories
+			if len(values) == 0 {
+				values = []string{""}
+			}
+		}
+
+		for _, v := range values {
+			if rule.re.MatchString(v) {
+				return rule, true
+			}
+		}
+	}
+	return Rule{}, false
+}
+
+// Records converts events into import records. Each event is given the
+// project, client, task and tags of the first matching rule; events without a
+// matching rule keep any Toggl metadata in their X- properties. All-day events
+// and events matched by a Skip rule are left out. A record's Line is the
+// 1-based position of its event in the calendar.
+func Records(events []Event, rules RuleSet) []importer.Record {
+	var records []importer.Record
+	for i, event := range events {
+		if event.AllDay || !event.End.After(event.Start) {
+			continue
+		}
+
+		record := importer.Record{
+			Line:        i + 1,
+			Description: event.Summary,
+			Start:       event.Start,
+			End:         event.End,
+			Tags:        append([]string{}, event.Categories...),
+		}
+
+		if rule, ok := rules.Match(event); ok {
+			if rule.Skip {
+				continue
+			}
+			record.Project = rule.Project
+			record.Client = rule.Client
+			record.Task = rule.Task
+			record.Billable = rule.Billable
+			for _, tag := range rule.Tags {
+				if !contains(record.Tags, tag) {
+					record.Tags = append(record.Tags, tag)
+				}
+			}
+		} else {
+			record.Project = event.Extra[PropProject]
+			record.Client = event.Extra[PropClient]
+			record.Billable = event.Extra[PropBillable] == "true"
+		}
+
+		records = append(records, record)
+	}
+	return records
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
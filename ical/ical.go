@@ -0,0 +1,335 @@
+/*
+Package ical converts between Toggl time entries and iCalendar (RFC 5545)
+VEVENTs.
+
+Only the parts of the format needed to exchange timed events are supported:
+VEVENT components with their start, end or duration, summary, description,
+location and categories. Other components, such as VTIMEZONE and VTODO, are
+ignored when reading.
+*/
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Event is a calendar event.
+type Event struct {
+	UID         string
+	Summary     string
+	Description string
+	Location    string
+	Categories  []string
+	Start       time.Time
+	End         time.Time
+
+	// AllDay is true for events that have dates rather than times.
+	AllDay bool
+
+	// Extra holds X- properties, keyed by property name.
+	Extra map[string]string
+}
+
+const (
+	utcLayout   = "20060102T150405Z"
+	localLayout = "20060102T150405"
+	dateLayout  = "20060102"
+)
+
+// WriteCalendar writes events as a VCALENDAR.
+func WriteCalendar(w io.Writer, events []Event) error {
+	cw := &calendarWriter{w: w}
+	cw.line("BEGIN:VCALENDAR")
+	cw.line("VERSION:2.0")
+	cw.line("PRODID:-//jason0x43//go-toggl//EN")
+	cw.line("CALSCALE:GREGORIAN")
+
+	stamp := time.Now().UTC().Format(utcLayout)
+	for _, e := range events {
+		cw.line("BEGIN:VEVENT")
+		cw.line("UID:" + escape(e.UID))
+		cw.line("DTSTAMP:" + stamp)
+		if e.AllDay {
+			cw.line("DTSTART;VALUE=DATE:" + e.Start.Format(dateLayout))
+			cw.line("DTEND;VALUE=DATE:" + e.End.Format(dateLayout))
+		} else {
+			cw.line("DTSTART:" + e.Start.UTC().Format(utcLayout))
+			cw.line("DTEND:" + e.End.UTC().Format(utcLayout))
+		}
+		cw.line("SUMMARY:" + escape(e.Summary))
+		if e.Description != "" {
+			cw.line("DESCRIPTION:" + escape(e.Description))
+		}
+		if e.Location != "" {
+			cw.line("LOCATION:" + escape(e.Location))
+		}
+		if len(e.Categories) > 0 {
+			var cats []string
+			for _, c := range e.Categories {
+				cats = append(cats, escape(c))
+			}
+			cw.line("CATEGORIES:" + strings.Join(cats, ","))
+		}
+		for _, name := range sortedKeys(e.Extra) {
+			cw.line(name + ":" + escape(e.Extra[name]))
+		}
+		cw.line("END:VEVENT")
+	}
+
+	cw.line("END:VCALENDAR")
+	return cw.err
+}
+
+// ReadCalendar reads the events in iCalendar data. Times with a TZID that
+// can't be loaded, and floating times, are interpreted in loc.
+func ReadCalendar(r io.Reader, loc *time.Location) ([]Event, error) {
+	if loc == nil {
+		loc = time.Local
+	}
+
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []Event
+	var event *Event
+	var duration time.Duration
+	depth := 0
+
+	for n, line := range lines {
+		name, params, value, err := parseLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", n+1, err)
+		}
+
+		switch {
+		case name == "BEGIN" && value == "VEVENT":
+			event = &Event{Extra: map[string]string{}}
+			duration = 0
+			depth = 0
+			continue
+		case event == nil:
+			continue
+		case name == "BEGIN":
+			// nested components such as VALARM
+			depth++
+			continue
+		case name == "END" && depth > 0:
+			depth--
+			continue
+		case depth > 0:
+			continue
+		case name == "END" && value == "VEVENT":
+			if event.End.IsZero() {
+				if duration == 0 && event.AllDay {
+					duration = 24 * time.Hour
+				}
+				event.End = event.Start.Add(duration)
+			}
+			events = append(events, *event)
+			event = nil
+			continue
+		}
+
+		switch name {
+		case "UID":
+			event.UID = unescape(value)
+		case "SUMMARY":
+			event.Summary = unescape(value)
+		case "DESCRIPTION":
+			event.Description = unescape(value)
+		case "LOCATION":
+			event.Location = unescape(value)
+		case "CATEGORIES":
+			for _, c := range splitUnescaped(value) {
+				if c = strings.TrimSpace(c); c != "" {
+					event.Categories = append(event.Categories, c)
+				}
+			}
+		case "DTSTART":
+			event.Start, event.AllDay, err = parseTime(value, params, loc)
+		case "DTEND":
+			event.End, _, err = parseTime(value, params, loc)
+		case "DURATION":
+			duration, err = parseDuration(value)
+		default:
+			if strings.HasPrefix(name, "X-") {
+				event.Extra[name] = unescape(value)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", n+1, err)
+		}
+	}
+
+	return events, nil
+}
+
+// support /////////////////////////////////////////////////////////////
+
+type calendarWriter struct {
+	w   io.Writer
+	err error
+}
+
+// line writes a content line, folding it at 75 octets.
+func (cw *calendarWriter) line(s string) {
+	if cw.err != nil {
+		return
+	}
+
+	var b strings.Builder
+	width := 0
+	for _, r := range s {
+		size := len(string(r))
+		if width+size > 75 {
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += size
+	}
+	b.WriteString("\r\n")
+
+	_, cw.err = io.WriteString(cw.w, b.String())
+}
+
+func unfold(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(line) > 0 && (line[0] == ' ' || line[0] == '\t') && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// parseLine splits a content line into its name, parameters and value.
+func parseLine(line string) (name string, params map[string]string, value string, err error) {
+	params = map[string]string{}
+
+	// find the colon separating the value, skipping quoted parameter values
+	quoted := false
+	colon := -1
+	for i, r := range line {
+		if r == '"' {
+			quoted = !quoted
+		} else if r == ':' && !quoted {
+			colon = i
+			break
+		}
+	}
+	if colon == -1 {
+		return "", nil, "", fmt.Errorf("invalid content line %q", line)
+	}
+
+	value = line[colon+1:]
+	parts := strings.Split(line[:colon], ";")
+	name = strings.ToUpper(parts[0])
+	for _, p := range parts[1:] {
+		kv := strings.SplitN(p, "=", 2)
+		if len(kv) == 2 {
+			params[strings.ToUpper(kv[0])] = strings.Trim(kv[1], `"`)
+		}
+	}
+	return
+}
+
+func parseTime(value string, params map[string]string, loc *time.Location) (t time.Time, allDay bool, err error) {
+	if params["VALUE"] == "DATE" || len(value) == len(dateLayout) {
+		t, err = time.ParseInLocation(dateLayout, value, loc)
+		return t, true, err
+	}
+
+	if strings.HasSuffix(value, "Z") {
+		t, err = time.Parse(utcLayout, value)
+		return
+	}
+
+	if tzid := params["TZID"]; tzid != "" {
+		if l, lerr := time.LoadLocation(tzid); lerr == nil {
+			loc = l
+		}
+	}
+	t, err = time.ParseInLocation(localLayout, value, loc)
+	return
+}
+
+var durationPattern = regexp.MustCompile(`^([+-])?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// parseDuration parses an iCalendar duration such as PT1H30M.
+func parseDuration(value string) (time.Duration, error) {
+	m := durationPattern.FindStringSubmatch(value)
+	if m == nil {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+
+	units := []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second}
+	var d time.Duration
+	for i, unit := range units {
+		if m[i+2] != "" {
+			n, _ := strconv.Atoi(m[i+2])
+			d += time.Duration(n) * unit
+		}
+	}
+	if m[1] == "-" {
+		d = -d
+	}
+	return d, nil
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`)
+
+func escape(s string) string {
+	return escaper.Replace(s)
+}
+
+func unescape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+			switch s[i] {
+			case 'n', 'N':
+				b.WriteByte('\n')
+			default:
+				b.WriteByte(s[i])
+			}
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// splitUnescaped splits a list value on commas that aren't escaped and
+// unescapes the items.
+func splitUnescaped(s string) []string {
+	var items []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			continue
+		}
+		if s[i] == ',' {
+			items = append(items, unescape(s[start:i]))
+			start = i + 1
+		}
+	}
+	return append(items, unescape(s[start:]))
+}
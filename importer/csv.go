@@ -0,0 +1,240 @@
+package importer
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Field identifies a piece of time entry data in an input file.
+type Field string
+
+// Fields that may be mapped to CSV columns
+const (
+	Description Field = "description"
+	Project     Field = "project"
+	Client      Field = "client"
+	Task        Field = "task"
+	Billable    Field = "billable"
+	StartDate   Field = "start_date"
+	StartTime   Field = "start_time"
+	EndDate     Field = "end_date"
+	EndTime     Field = "end_time"
+	Duration    Field = "duration"
+	Tags        Field = "tags"
+)
+
+// Format describes the layout of a CSV file.
+type Format struct {
+	// Columns maps fields to column names in the file's header. StartDate and
+	// StartTime are required, as is either EndTime or Duration.
+	Columns map[Field]string
+
+	DateLayout string
+	TimeLayout string
+
+	// TagSeparator separates tags within the tags column.
+	TagSeparator string
+
+	// Location is the time zone of the file's dates and times. If nil, the
+	// local time zone is used.
+	Location *time.Location
+}
+
+// TogglFormat is the format of Toggl's own CSV export.
+var TogglFormat = Format{
+	Columns: map[Field]string{
+		Description: "Description",
+		Project:     "Project",
+		Client:      "Client",
+		Task:        "Task",
+		Billable:    "Billable",
+		StartDate:   "Start date",
+		StartTime:   "Start time",
+		EndDate:     "End date",
+		EndTime:     "End time",
+		Duration:    "Duration",
+		Tags:        "Tags",
+	},
+	DateLayout:   "2006-01-02",
+	TimeLayout:   "15:04:05",
+	TagSeparator: ",",
+}
+
+// WithColumns returns a copy of the format with some of its column mappings
+// replaced. The mapping is given as a comma-separated list of field=column
+// pairs, such as "description=Notes,project=Job".
+func (f Format) WithColumns(mapping string) (Format, error) {
+	columns := map[Field]string{}
+	for k, v := range f.Columns {
+		columns[k] = v
+	}
+
+	for _, pair := range strings.Split(mapping, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 {
+			return f, fmt.Errorf("invalid column mapping %q", pair)
+		}
+		columns[Field(strings.TrimSpace(parts[0]))] = strings.TrimSpace(parts[1])
+	}
+
+	f.Columns = columns
+	return f, nil
+}
+
+// ReadCSV reads records from CSV data with a header line. Rows that can't be
+// parsed are reported as issues rather than causing the whole read to fail.
+func ReadCSV(r io.Reader, format Format) ([]Record, []Issue, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("Error reading header: %v", err)
+	}
+
+	indices := map[Field]int{}
+	for field, name := range format.Columns {
+		for i, column := range header {
+			if strings.EqualFold(strings.TrimSpace(column), name) {
+				indices[field] = i
+				break
+			}
+		}
+	}
+	if _, ok := indices[StartDate]; !ok {
+		return nil, nil, fmt.Errorf("missing start date column %q", format.Columns[StartDate])
+	}
+	if _, ok := indices[StartTime]; !ok {
+		return nil, nil, fmt.Errorf("missing start time column %q", format.Columns[StartTime])
+	}
+	_, hasEnd := indices[EndTime]
+	_, hasDuration := indices[Duration]
+	if !hasEnd && !hasDuration {
+		return nil, nil, fmt.Errorf("missing end time or duration column")
+	}
+
+	loc := format.Location
+	if loc == nil {
+		loc = time.Local
+	}
+
+	var records []Record
+	var issues []Issue
+	line := 1
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			issues = append(issues, Issue{Line: line, Message: err.Error()})
+			continue
+		}
+
+		value := func(field Field) string {
+			if i, ok := indices[field]; ok && i < len(row) {
+				return strings.TrimSpace(row[i])
+			}
+			return ""
+		}
+
+		record, err := format.parseRow(value, loc)
+		if err != nil {
+			issues = append(issues, Issue{Line: line, Message: err.Error()})
+			continue
+		}
+		record.Line = line
+		records = append(records, record)
+	}
+
+	return records, issues, nil
+}
+
+func (f Format) parseRow(value func(Field) string, loc *time.Location) (Record, error) {
+	record := Record{
+		Description: value(Description),
+		Project:     value(Project),
+		Client:      value(Client),
+		Task:        value(Task),
+	}
+
+	switch strings.ToLower(value(Billable)) {
+	case "yes", "true", "1":
+		record.Billable = true
+	}
+
+	for _, tag := range strings.Split(value(Tags), f.TagSeparator) {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			record.Tags = append(record.Tags, tag)
+		}
+	}
+
+	layout := f.DateLayout + " " + f.TimeLayout
+	start, err := time.ParseInLocation(layout, value(StartDate)+" "+value(StartTime), loc)
+	if err != nil {
+		return record, fmt.Errorf("invalid start: %v", err)
+	}
+	record.Start = start
+
+	if value(EndTime) != "" {
+		endDate := value(EndDate)
+		if endDate == "" {
+			endDate = value(StartDate)
+		}
+		end, err := time.ParseInLocation(layout, endDate+" "+value(EndTime), loc)
+		if err != nil {
+			return record, fmt.Errorf("invalid end: %v", err)
+		}
+		if end.Before(start) && value(EndDate) == "" {
+			end = end.AddDate(0, 0, 1)
+		}
+		record.End = end
+	} else {
+		duration, err := parseDuration(value(Duration))
+		if err != nil {
+			return record, fmt.Errorf("invalid duration: %v", err)
+		}
+		record.End = start.Add(duration)
+	}
+
+	if !record.End.After(record.Start) {
+		return record, fmt.Errorf("end is not after start")
+	}
+
+	return record, nil
+}
+
+// parseDuration parses durations written as HH:MM:SS, HH:MM, a decimal
+// number of hours, or a Go duration string.
+func parseDuration(s string) (time.Duration, error) {
+	if strings.Contains(s, ":") {
+		parts := strings.Split(s, ":")
+		if len(parts) > 3 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		var d time.Duration
+		units := []time.Duration{time.Hour, time.Minute, time.Second}
+		for i, part := range parts {
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration %q", s)
+			}
+			d += time.Duration(n) * units[i]
+		}
+		return d, nil
+	}
+
+	if hours, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Duration(hours * float64(time.Hour)), nil
+	}
+
+	return time.ParseDuration(s)
+}
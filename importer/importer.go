@@ -0,0 +1,412 @@
+/*
+Package importer creates Toggl time entries from external data.
+
+Importing is done in two steps. Records read from an input file are first
+resolved against the user's account and existing time entries to produce a
+Plan, which can be inspected or printed as a dry-run diff. Applying the plan
+creates the new entries.
+
+Every imported entry carries a marker derived from a stable hash of its source
+record, either as a tag or at the end of its description. Records whose marker
+already exists in Toggl are skipped, so importing the same file twice is safe.
+*/
+package importer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jason0x43/go-toggl"
+)
+
+// MarkerPrefix begins every import marker.
+const MarkerPrefix = "import:"
+
+// MarkerStyle determines where import markers are stored.
+type MarkerStyle int
+
+const (
+	// TagMarker stores the marker as a tag.
+	TagMarker MarkerStyle = iota
+
+	// DescriptionMarker appends the marker to the entry's description in
+	// square brackets.
+	DescriptionMarker
+)
+
+// Record is a time entry read from an input file.
+type Record struct {
+	Line        int
+	Description string
+	Project     string
+	Client      string
+	Task        string
+	Billable    bool
+	Start       time.Time
+	End         time.Time
+	Tags        []string
+}
+
+// Hash returns a stable identifier for the record's content.
+func (r Record) Hash() string {
+	h := sha256.New()
+	fmt.Fprintf(
+		h,
+		"%d\x00%d\x00%s\x00%s\x00%s\x00%s",
+		r.Start.Unix(),
+		r.End.Unix(),
+		r.Description,
+		r.Project,
+		r.Client,
+		r.Task,
+	)
+	return hex.EncodeToString(h.Sum(nil))[:12]
+}
+
+// Issue is a problem found while reading or planning an import.
+type Issue struct {
+	Line    int
+	Message string
+	Warning bool
+}
+
+func (i Issue) String() string {
+	kind := "error"
+	if i.Warning {
+		kind = "warning"
+	}
+	return fmt.Sprintf("line %d: %s: %s", i.Line, kind, i.Message)
+}
+
+// Action is what a plan will do with a record.
+type Action int
+
+// Plan actions
+const (
+	Create Action = iota
+	Skip
+	Invalid
+)
+
+// Change is a planned action for a single record.
+type Change struct {
+	Action Action
+	Record Record
+	Entry  toggl.TimeEntry
+}
+
+// Plan is the set of changes an import will make.
+type Plan struct {
+	Changes []Change
+	Issues  []Issue
+}
+
+// HasErrors returns true if any of the plan's issues are errors.
+func (p Plan) HasErrors() bool {
+	for _, issue := range p.Issues {
+		if !issue.Warning {
+			return true
+		}
+	}
+	return false
+}
+
+// Importer resolves records and creates time entries.
+type Importer struct {
+	Session *toggl.Session
+
+	// Account provides the projects, clients, tasks and tags that record names
+	// are resolved against.
+	Account toggl.Account
+
+	// Workspace is the workspace that entries are created in.
+	Workspace int
+
+	Marker MarkerStyle
+
+	// AllowOverlaps allows records that overlap other records or existing
+	// entries to be imported.
+	AllowOverlaps bool
+}
+
+// Plan resolves records and determines which ones need to be created. It
+// retrieves existing time entries covering the records' time range from Toggl
+// to detect previously imported records and overlaps.
+func (imp *Importer) Plan(records []Record) (Plan, error) {
+	var plan Plan
+	if len(records) == 0 {
+		return plan, nil
+	}
+
+	first, last := records[0].Start, records[0].End
+	for _, r := range records {
+		if r.Start.Before(first) {
+			first = r.Start
+		}
+		if r.End.After(last) {
+			last = r.End
+		}
+	}
+
+	existing, err := imp.Session.GetTimeEntries(first.AddDate(0, 0, -1), last.AddDate(0, 0, 1))
+	if err != nil {
+		return plan, err
+	}
+
+	imported := map[string]bool{}
+	for _, e := range existing {
+		if marker := findMarker(e); marker != "" {
+			imported[marker] = true
+		}
+	}
+
+	knownTags := map[string]bool{}
+	for _, t := range imp.Account.Tags {
+		if t.Wid == imp.Workspace {
+			knownTags[t.Name] = true
+		}
+	}
+
+	// known holds existing entries plus the entries the plan will create
+	known := existing
+	for _, record := range records {
+		change := Change{Record: record}
+		hash := record.Hash()
+
+		if imported[hash] {
+			change.Action = Skip
+			plan.Changes = append(plan.Changes, change)
+			continue
+		}
+
+		entry, problems := imp.resolve(record)
+		for _, tag := range record.Tags {
+			if !knownTags[tag] {
+				plan.Issues = append(plan.Issues, Issue{
+					Line:    record.Line,
+					Message: fmt.Sprintf("tag %q will be created", tag),
+					Warning: true,
+				})
+			}
+		}
+
+		if !imp.AllowOverlaps {
+			for _, other := range known {
+				if overlaps(entry, other) {
+					problems = append(problems, fmt.Sprintf(
+						"overlaps %q at %s",
+						other.Description,
+						other.StartTime().In(record.Start.Location()).Format("2006-01-02 15:04"),
+					))
+					break
+				}
+			}
+		}
+
+		if len(problems) > 0 {
+			for _, p := range problems {
+				plan.Issues = append(plan.Issues, Issue{Line: record.Line, Message: p})
+			}
+			change.Action = Invalid
+			plan.Changes = append(plan.Changes, change)
+			continue
+		}
+
+		imp.addMarker(&entry, hash)
+		change.Action = Create
+		change.Entry = entry
+		plan.Changes = append(plan.Changes, change)
+		known = append(known, entry)
+		imported[hash] = true
+	}
+
+	sort.SliceStable(plan.Issues, func(i, j int) bool {
+		return plan.Issues[i].Line < plan.Issues[j].Line
+	})
+
+	return plan, nil
+}
+
+// Apply creates the time entries for a plan's Create changes, returning the
+// entries that were created. It stops at the first error.
+func (imp *Importer) Apply(plan Plan) ([]toggl.TimeEntry, error) {
+	var created []toggl.TimeEntry
+	for _, change := range plan.Changes {
+		if change.Action != Create {
+			continue
+		}
+		entry, err := imp.Session.CreateTimeEntry(change.Entry)
+		if err != nil {
+			return created, fmt.Errorf("line %d: %v", change.Record.Line, err)
+		}
+		created = append(created, entry)
+	}
+	return created, nil
+}
+
+// WriteDiff writes a human readable summary of a plan. Lines to be created are
+// prefixed with "+", already imported lines with "=", and invalid lines with
+// "!".
+func (p Plan) WriteDiff(w io.Writer) error {
+	var creates, skips int
+	for _, change := range p.Changes {
+		prefix := "+"
+		switch change.Action {
+		case Create:
+			creates++
+		case Skip:
+			prefix = "="
+			skips++
+		case Invalid:
+			prefix = "!"
+		}
+
+		r := change.Record
+		project := r.Project
+		if r.Client != "" {
+			project += " (" + r.Client + ")"
+		}
+		_, err := fmt.Fprintf(
+			w,
+			"%s line %d: %s-%s %s %q %v\n",
+			prefix,
+			r.Line,
+			r.Start.Format("2006-01-02 15:04"),
+			r.End.Format("15:04"),
+			project,
+			r.Description,
+			r.Tags,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, issue := range p.Issues {
+		if _, err := fmt.Fprintln(w, issue); err != nil {
+			return err
+		}
+	}
+
+	_, err := fmt.Fprintf(
+		w,
+		"%d to create, %d already imported, %d invalid\n",
+		creates,
+		skips,
+		len(p.Changes)-creates-skips,
+	)
+	return err
+}
+
+// resolve converts a record into a time entry, resolving project, client and
+// task names to IDs.
+func (imp *Importer) resolve(record Record) (entry toggl.TimeEntry, problems []string) {
+	start, end := record.Start, record.End
+	entry = toggl.TimeEntry{
+		Wid:         imp.Workspace,
+		Description: record.Description,
+		Start:       &start,
+		Stop:        &end,
+		Duration:    int64(end.Sub(start) / time.Second),
+		Billable:    record.Billable,
+		Tags:        append([]string{}, record.Tags...),
+	}
+
+	if record.Project == "" {
+		return
+	}
+
+	clientIDs := map[int]bool{}
+	if record.Client != "" {
+		for _, c := range imp.Account.Clients {
+			if c.Wid == imp.Workspace && strings.EqualFold(c.Name, record.Client) {
+				clientIDs[c.ID] = true
+			}
+		}
+		if len(clientIDs) == 0 {
+			problems = append(problems, fmt.Sprintf("client %q not found", record.Client))
+			return
+		}
+	}
+
+	var matches []toggl.Project
+	for _, p := range imp.Account.Projects {
+		if p.Wid != imp.Workspace || !strings.EqualFold(p.Name, record.Project) {
+			continue
+		}
+		if record.Client != "" && (p.Cid == nil || !clientIDs[*p.Cid]) {
+			continue
+		}
+		matches = append(matches, p)
+	}
+
+	switch len(matches) {
+	case 0:
+		problems = append(problems, fmt.Sprintf("project %q not found", record.Project))
+		return
+	case 1:
+	default:
+		problems = append(problems, fmt.Sprintf("project %q is ambiguous; specify a client", record.Project))
+		return
+	}
+
+	pid := matches[0].ID
+	entry.Pid = &pid
+
+	if record.Task != "" {
+		for _, t := range imp.Account.Tasks {
+			if t.Pid == pid && strings.EqualFold(t.Name, record.Task) {
+				tid := t.ID
+				entry.Tid = &tid
+				break
+			}
+		}
+		if entry.Tid == nil {
+			problems = append(problems, fmt.Sprintf("task %q not found", record.Task))
+		}
+	}
+
+	return
+}
+
+func (imp *Importer) addMarker(entry *toggl.TimeEntry, hash string) {
+	marker := MarkerPrefix + hash
+	if imp.Marker == DescriptionMarker {
+		entry.Description = strings.TrimSpace(entry.Description + " [" + marker + "]")
+	} else {
+		entry.AddTag(marker)
+	}
+}
+
+// findMarker returns the import hash stored in an entry, if any.
+func findMarker(entry toggl.TimeEntry) string {
+	for _, tag := range entry.Tags {
+		if strings.HasPrefix(tag, MarkerPrefix) {
+			return strings.TrimPrefix(tag, MarkerPrefix)
+		}
+	}
+
+	desc := entry.Description
+	if i := strings.LastIndex(desc, "["+MarkerPrefix); i != -1 && strings.HasSuffix(desc, "]") {
+		return desc[i+len(MarkerPrefix)+1 : len(desc)-1]
+	}
+
+	return ""
+}
+
+func overlaps(a, b toggl.TimeEntry) bool {
+	if a.Start == nil || b.Start == nil {
+		return false
+	}
+	aEnd := a.StartTime().Add(time.Duration(a.Duration) * time.Second)
+	bEnd := b.StartTime().Add(time.Duration(b.Duration) * time.Second)
+	if b.IsRunning() {
+		bEnd = time.Now()
+	}
+	return a.Start.Before(bEnd) && b.Start.Before(aEnd)
+}
@@ -0,0 +1,66 @@
+package toggl
+
+// Index provides lookups of an account's workspaces, clients, projects and
+// tasks by ID.
+type Index struct {
+	Workspaces map[int]Workspace
+	Clients    map[int]Client
+	Projects   map[int]Project
+	Tasks      map[int]Task
+}
+
+// NewIndex creates an Index from the related data in an account.
+func NewIndex(account Account) Index {
+	index := Index{
+		Workspaces: map[int]Workspace{},
+		Clients:    map[int]Client{},
+		Projects:   map[int]Project{},
+		Tasks:      map[int]Task{},
+	}
+
+	for _, w := range account.Workspaces {
+		index.Workspaces[w.ID] = w
+	}
+	for _, c := range account.Clients {
+		index.Clients[c.ID] = c
+	}
+	for _, p := range account.Projects {
+		index.Projects[p.ID] = p
+	}
+	for _, t := range account.Tasks {
+		index.Tasks[t.ID] = t
+	}
+
+	return index
+}
+
+// ProjectName returns the name of the project with a given ID, or an empty
+// string if pid is nil or unknown.
+func (index Index) ProjectName(pid *int) string {
+	if pid == nil {
+		return ""
+	}
+	return index.Projects[*pid].Name
+}
+
+// ClientName returns the name of the client of the project with a given ID,
+// or an empty string if the project has no known client.
+func (index Index) ClientName(pid *int) string {
+	if pid == nil {
+		return ""
+	}
+	project, ok := index.Projects[*pid]
+	if !ok || project.Cid == nil {
+		return ""
+	}
+	return index.Clients[*project.Cid].Name
+}
+
+// TaskName returns the name of the task with a given ID, or an empty string
+// if tid is nil or unknown.
+func (index Index) TaskName(tid *int) string {
+	if tid == nil {
+		return ""
+	}
+	return index.Tasks[*tid].Name
+}
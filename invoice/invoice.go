@@ -0,0 +1,213 @@
+/*
+Package invoice turns billable Toggl time into invoices.
+
+An invoice covers the billable, stopped time entries for one client's projects
+within a date range. Entries are grouped into line items by project and task,
+with each entry's duration rounded according to the workspace's rounding
+settings and priced at the most specific hourly rate available: the user's
+rate for the project, the project's rate, or the workspace default. Every line
+item lists the IDs of the entries it was built from.
+*/
+package invoice
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jason0x43/go-toggl"
+	"github.com/jason0x43/go-toggl/rounding"
+)
+
+// Rates provides hourly rates for time entries.
+type Rates struct {
+	// Default is the workspace's default hourly rate.
+	Default float64
+
+	// Projects maps project IDs to project rates.
+	Projects map[int]float64
+
+	// ProjectUsers maps project IDs and user IDs to a user's rate for a
+	// project.
+	ProjectUsers map[ProjectUser]float64
+}
+
+// ProjectUser identifies a user's membership in a project.
+type ProjectUser struct {
+	Pid int
+	Uid int
+}
+
+// NewRates collects the rates defined for a workspace, its projects, and its
+// project users.
+func NewRates(workspace toggl.Workspace, projects []toggl.Project, projectUsers []toggl.ProjectUser) Rates {
+	rates := Rates{
+		Default:      workspace.DefaultHourlyRate,
+		Projects:     map[int]float64{},
+		ProjectUsers: map[ProjectUser]float64{},
+	}
+	for _, p := range projects {
+		if p.Wid == workspace.ID && p.Rate != nil {
+			rates.Projects[p.ID] = *p.Rate
+		}
+	}
+	for _, pu := range projectUsers {
+		if pu.Wid == workspace.ID && pu.Rate != nil {
+			rates.ProjectUsers[ProjectUser{Pid: pu.Pid, Uid: pu.Uid}] = *pu.Rate
+		}
+	}
+	return rates
+}
+
+// Rate returns the hourly rate for a user's time on a project.
+func (r Rates) Rate(pid, uid int) float64 {
+	if rate, ok := r.ProjectUsers[ProjectUser{Pid: pid, Uid: uid}]; ok {
+		return rate
+	}
+	if rate, ok := r.Projects[pid]; ok {
+		return rate
+	}
+	return r.Default
+}
+
+// LineItem is billable time for a single project task at a single rate.
+type LineItem struct {
+	ProjectID int
+	Project   string
+	TaskID    int
+	Task      string
+	Duration  time.Duration
+	Rate      float64
+	Amount    float64
+	EntryIDs  []int
+}
+
+// Description returns the item's project and task names.
+func (item LineItem) Description() string {
+	if item.Task != "" {
+		return item.Project + ": " + item.Task
+	}
+	return item.Project
+}
+
+// Invoice is a list of charges for a client over a period of time.
+type Invoice struct {
+	Number   string
+	Issued   time.Time
+	Client   toggl.Client
+	Start    time.Time
+	End      time.Time
+	Currency string
+	Items    []LineItem
+	Duration time.Duration
+	Total    float64
+}
+
+// Options describe the invoice to be created.
+type Options struct {
+	Number string
+	Client toggl.Client
+
+	// Start and End bound the start times of the entries to include.
+	Start time.Time
+	End   time.Time
+
+	// Workspace provides rounding settings and the default currency.
+	Workspace toggl.Workspace
+
+	Rates Rates
+
+	// Index resolves project and task names, and determines which projects
+	// belong to the client.
+	Index toggl.Index
+}
+
+// New creates an invoice from the billable time entries for the client's
+// projects. Entries that are running, not billable, outside the date range, or
+// for other clients are ignored.
+func New(entries []toggl.TimeEntry, opts Options) Invoice {
+	inv := Invoice{
+		Number:   opts.Number,
+		Issued:   time.Now(),
+		Client:   opts.Client,
+		Start:    opts.Start,
+		End:      opts.End,
+		Currency: opts.Workspace.DefaultCurrency,
+	}
+
+	type key struct {
+		pid  int
+		tid  int
+		rate float64
+	}
+	items := map[key]*LineItem{}
+	rule := rounding.FromWorkspace(opts.Workspace)
+
+	for _, e := range entries {
+		if !e.Billable || e.IsRunning() || e.Start == nil || e.Pid == nil {
+			continue
+		}
+		if e.Start.Before(opts.Start) || !e.Start.Before(opts.End) {
+			continue
+		}
+		project, ok := opts.Index.Projects[*e.Pid]
+		if !ok || project.Cid == nil || *project.Cid != opts.Client.ID {
+			continue
+		}
+		if project.Currency != nil && inv.Currency == "" {
+			inv.Currency = *project.Currency
+		}
+
+		k := key{pid: project.ID, rate: opts.Rates.Rate(project.ID, e.Uid)}
+		if e.Tid != nil {
+			k.tid = *e.Tid
+		}
+
+		item, ok := items[k]
+		if !ok {
+			item = &LineItem{
+				ProjectID: project.ID,
+				Project:   project.Name,
+				TaskID:    k.tid,
+				Task:      opts.Index.TaskName(e.Tid),
+				Rate:      k.rate,
+			}
+			items[k] = item
+		}
+
+		item.Duration += rule.EntryDuration(e)
+		item.EntryIDs = append(item.EntryIDs, e.ID)
+	}
+
+	for _, item := range items {
+		item.Amount = roundCents(item.Duration.Hours() * item.Rate)
+		sort.Ints(item.EntryIDs)
+		inv.Items = append(inv.Items, *item)
+		inv.Duration += item.Duration
+		inv.Total += item.Amount
+	}
+	inv.Total = roundCents(inv.Total)
+
+	sort.Slice(inv.Items, func(i, j int) bool {
+		a, b := inv.Items[i], inv.Items[j]
+		if a.Description() != b.Description() {
+			return a.Description() < b.Description()
+		}
+		return a.Rate < b.Rate
+	})
+
+	return inv
+}
+
+func roundCents(amount float64) float64 {
+	cents := amount * 100
+	if cents < 0 {
+		return float64(int64(cents-0.5)) / 100
+	}
+	return float64(int64(cents+0.5)) / 100
+}
+
+func formatHours(d time.Duration) string {
+	minutes := int64(d.Round(time.Minute) / time.Minute)
+	return fmt.Sprintf("%d:%02d", minutes/60, minutes%60)
+}
@@ -0,0 +1,187 @@
+package invoice
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+const dateLayout = "2006-01-02"
+
+// WriteText writes the invoice as plain text.
+func (inv Invoice) WriteText(w io.Writer) error {
+	_, err := fmt.Fprintf(
+		w,
+		"Invoice %s\nClient: %s\nIssued: %s\nPeriod: %s\n\n",
+		inv.Number,
+		inv.Client.Name,
+		inv.Issued.Format(dateLayout),
+		inv.period(),
+	)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "Item\tHours\tRate\tAmount\t\n")
+	for _, item := range inv.Items {
+		fmt.Fprintf(
+			tw,
+			"%s\t%s\t%s\t%s\t\n",
+			item.Description(),
+			formatHours(item.Duration),
+			inv.money(item.Rate),
+			inv.money(item.Amount),
+		)
+	}
+	fmt.Fprintf(tw, "Total\t%s\t\t%s\t\n", formatHours(inv.Duration), inv.money(inv.Total))
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(w)
+	for i, item := range inv.Items {
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "[%d] %s: entries %s\n", i+1, item.Description(), joinIDs(item.EntryIDs))
+	}
+	return err
+}
+
+// WriteMarkdown writes the invoice as a Markdown document.
+func (inv Invoice) WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# Invoice %s\n\n", inv.Number)
+	fmt.Fprintf(&b, "- **Client:** %s\n", escapeMarkdown(inv.Client.Name))
+	fmt.Fprintf(&b, "- **Issued:** %s\n", inv.Issued.Format(dateLayout))
+	fmt.Fprintf(&b, "- **Period:** %s\n\n", inv.period())
+
+	b.WriteString("| Item | Hours | Rate | Amount | Entries |\n")
+	b.WriteString("| --- | ---: | ---: | ---: | --- |\n")
+	for _, item := range inv.Items {
+		fmt.Fprintf(
+			&b,
+			"| %s | %s | %s | %s | %s |\n",
+			escapeMarkdown(item.Description()),
+			formatHours(item.Duration),
+			inv.money(item.Rate),
+			inv.money(item.Amount),
+			joinIDs(item.EntryIDs),
+		)
+	}
+	fmt.Fprintf(
+		&b,
+		"| **Total** | **%s** | | **%s** | |\n",
+		formatHours(inv.Duration),
+		inv.money(inv.Total),
+	)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+var htmlTemplate = template.Must(template.New("invoice").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Invoice {{.Invoice.Number}}</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; }
+th, td { padding: 0.25em 0.75em; border-bottom: 1px solid #ccc; }
+.num { text-align: right; }
+.entries { color: #888; font-size: smaller; }
+</style>
+</head>
+<body>
+<h1>Invoice {{.Invoice.Number}}</h1>
+<dl>
+<dt>Client</dt><dd>{{.Invoice.Client.Name}}</dd>
+<dt>Issued</dt><dd>{{.Issued}}</dd>
+<dt>Period</dt><dd>{{.Period}}</dd>
+</dl>
+<table>
+<thead>
+<tr><th>Item</th><th class="num">Hours</th><th class="num">Rate</th><th class="num">Amount</th><th>Entries</th></tr>
+</thead>
+<tbody>
+{{- range .Items}}
+<tr><td>{{.Description}}</td><td class="num">{{.Hours}}</td><td class="num">{{.Rate}}</td><td class="num">{{.Amount}}</td><td class="entries">{{.Entries}}</td></tr>
+{{- end}}
+</tbody>
+<tfoot>
+<tr><th>Total</th><th class="num">{{.Hours}}</th><th></th><th class="num">{{.Total}}</th><th></th></tr>
+</tfoot>
+</table>
+</body>
+</html>
+`))
+
+// WriteHTML writes the invoice as a standalone HTML document.
+func (inv Invoice) WriteHTML(w io.Writer) error {
+	type htmlItem struct {
+		Description string
+		Hours       string
+		Rate        string
+		Amount      string
+		Entries     string
+	}
+
+	data := struct {
+		Invoice Invoice
+		Issued  string
+		Period  string
+		Items   []htmlItem
+		Hours   string
+		Total   string
+	}{
+		Invoice: inv,
+		Issued:  inv.Issued.Format(dateLayout),
+		Period:  inv.period(),
+		Hours:   formatHours(inv.Duration),
+		Total:   inv.money(inv.Total),
+	}
+	for _, item := range inv.Items {
+		data.Items = append(data.Items, htmlItem{
+			Description: item.Description(),
+			Hours:       formatHours(item.Duration),
+			Rate:        inv.money(item.Rate),
+			Amount:      inv.money(item.Amount),
+			Entries:     joinIDs(item.EntryIDs),
+		})
+	}
+
+	return htmlTemplate.Execute(w, data)
+}
+
+func (inv Invoice) period() string {
+	// End is exclusive, so show the last day included
+	return inv.Start.Format(dateLayout) + " to " + inv.End.AddDate(0, 0, -1).Format(dateLayout)
+}
+
+func (inv Invoice) money(amount float64) string {
+	s := strconv.FormatFloat(amount, 'f', 2, 64)
+	if inv.Currency != "" {
+		s += " " + inv.Currency
+	}
+	return s
+}
+
+func joinIDs(ids []int) string {
+	s := make([]string, len(ids))
+	for i, id := range ids {
+		s[i] = strconv.Itoa(id)
+	}
+	return strings.Join(s, ", ")
+}
+
+var markdownEscaper = strings.NewReplacer(`|`, `\|`, `*`, `\*`, `_`, `\_`)
+
+func escapeMarkdown(s string) string {
+	return markdownEscaper.Replace(s)
+}
@@ -0,0 +1,343 @@
+package toggl
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// JournalEntry records a change made to a time entry, project, client or tag.
+type JournalEntry struct {
+	Time      time.Time `json:"time"`
+	Operation string    `json:"operation,omitempty"`
+
+	// Action is "create", "update" or "delete".
+	Action string `json:"action"`
+
+	// Kind is the type of object changed: "time_entries", "projects",
+	// "clients" or "tags".
+	Kind string `json:"kind"`
+	Wid  int    `json:"workspace_id"`
+	ID   int    `json:"id"`
+
+	// Before is the object as it was before an update or delete.
+	Before json.RawMessage `json:"before,omitempty"`
+
+	// After is the object returned by Toggl after a create or update.
+	After json.RawMessage `json:"after,omitempty"`
+}
+
+// Journal is a local log of the changes a session makes, which Session.Undo
+// can revert. The full object is fetched from Toggl before each update or
+// delete so that it can be restored.
+type Journal struct {
+	path string
+	mu   sync.Mutex
+}
+
+var journalPathPattern = regexp.MustCompile(
+	`^/workspaces/(\d+)/(time_entries|projects|clients|tags)(?:/(\d+)(/stop)?)?$`,
+)
+
+// OpenJournal opens a journal stored at path, creating the file if needed.
+func OpenJournal(path string) (*Journal, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("Error opening journal: %v", err)
+	}
+	f.Close()
+	return &Journal{path: path}, nil
+}
+
+// SetJournal records the session's changes in j, or stops recording if j is
+// nil. Nothing is recorded in dry-run mode.
+func (session *Session) SetJournal(j *Journal) {
+	session.journal = j
+}
+
+// Journal returns the session's journal, or nil.
+func (session *Session) Journal() *Journal {
+	return session.journal
+}
+
+// Entries returns the journal's entries, oldest first.
+func (j *Journal) Entries() ([]JournalEntry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.read()
+}
+
+func (j *Journal) read() ([]JournalEntry, error) {
+	f, err := os.Open(j.path)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading journal: %v", err)
+	}
+	defer f.Close()
+
+	var entries []JournalEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry JournalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("Error reading journal: %v", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+func (j *Journal) append(entry JournalEntry) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	f, err := os.OpenFile(j.path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("Error writing journal: %v", err)
+	}
+	defer f.Close()
+	return json.NewEncoder(f).Encode(entry)
+}
+
+// truncate removes the last n entries.
+func (j *Journal) truncate(n int) error {
+	entries, err := j.read()
+	if err != nil {
+		return err
+	}
+	if n > len(entries) {
+		n = len(entries)
+	}
+
+	tmp := j.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("Error writing journal: %v", err)
+	}
+	enc := json.NewEncoder(f)
+	for _, entry := range entries[:len(entries)-n] {
+		if err := enc.Encode(entry); err != nil {
+			f.Close()
+			return fmt.Errorf("Error writing journal: %v", err)
+		}
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, j.path)
+}
+
+// wrap returns a RoundTripFunc that journals the changes made by mutating
+// requests sent with next.
+func (j *Journal) wrap(next RoundTripFunc) RoundTripFunc {
+	return func(req *Request) (*Response, error) {
+		m := journalPathPattern.FindStringSubmatch(req.Path)
+		if !req.IsMutation() || req.API != TogglAPI || m == nil {
+			return next(req)
+		}
+
+		entry := JournalEntry{Operation: req.Operation, Kind: m[2]}
+		entry.Wid, _ = strconv.Atoi(m[1])
+		entry.ID, _ = strconv.Atoi(m[3])
+
+		switch {
+		case req.Method == "POST" && m[3] == "":
+			entry.Action = "create"
+		case req.Method == "DELETE":
+			entry.Action = "delete"
+		default:
+			entry.Action = "update"
+		}
+
+		if entry.Action != "create" {
+			before, err := j.snapshot(next, req, entry)
+			if err != nil {
+				return nil, fmt.Errorf("Error saving undo snapshot: %v", err)
+			}
+			entry.Before = before
+		}
+
+		resp, err := next(req)
+		if err != nil {
+			return resp, err
+		}
+
+		if entry.Action != "delete" && resp != nil && len(resp.Body) > 0 {
+			entry.After = json.RawMessage(resp.Body)
+			if entry.Action == "create" {
+				var created struct {
+					ID int `json:"id"`
+				}
+				json.Unmarshal(resp.Body, &created)
+				entry.ID = created.ID
+			}
+		}
+		entry.Time = time.Now()
+
+		if err := j.append(entry); err != nil {
+			return resp, err
+		}
+		return resp, nil
+	}
+}
+
+// snapshot fetches the current state of the object a request changes.
+func (j *Journal) snapshot(next RoundTripFunc, req *Request, entry JournalEntry) (json.RawMessage, error) {
+	get := &Request{
+		Context:   req.Context,
+		Operation: req.Operation,
+		Method:    "GET",
+		API:       TogglAPI,
+		Path:      generateResourceURLWithID(kindResourceTypes[entry.Kind], entry.Wid, entry.ID),
+		Header:    req.Header.Clone(),
+	}
+	switch entry.Kind {
+	case "time_entries":
+		get.Path = generateUserResourceURL(timeEntries) + fmt.Sprintf("/%d", entry.ID)
+	case "tags":
+		// tags can only be listed
+		get.Path = generateResourceURL(tags, entry.Wid)
+	}
+
+	resp, err := next(get)
+	if err != nil {
+		return nil, err
+	}
+	if entry.Kind != "tags" {
+		return json.RawMessage(resp.Body), nil
+	}
+
+	var list []json.RawMessage
+	if err := json.Unmarshal(resp.Body, &list); err != nil {
+		return nil, err
+	}
+	for _, item := range list {
+		var tag struct {
+			ID int `json:"id"`
+		}
+		if json.Unmarshal(item, &tag) == nil && tag.ID == entry.ID {
+			return item, nil
+		}
+	}
+	return nil, fmt.Errorf("tag %d not found", entry.ID)
+}
+
+var kindResourceTypes = map[string]resourceType{
+	"time_entries": timeEntries,
+	"projects":     projects,
+	"clients":      clients,
+	"tags":         tags,
+}
+
+// Undo reverts the last n changes in the session's journal, newest first:
+// created objects are deleted, updated objects get their previous values
+// back, and deleted objects are recreated. Recreated objects get new IDs,
+// which later undos in the same call take into account. The reverted entries
+// are removed from the journal and returned. If an undo fails, the entries
+// reverted before it are still removed. In dry-run mode nothing is reverted,
+// so the journal is left as it is.
+func (session *Session) Undo(n int) ([]JournalEntry, error) {
+	j := session.journal
+	if j == nil {
+		return nil, fmt.Errorf("session has no journal")
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	entries, err := j.read()
+	if err != nil {
+		return nil, err
+	}
+	if n > len(entries) {
+		n = len(entries)
+	}
+
+	// changes made while undoing aren't journaled
+	s := *session
+	s.journal = nil
+
+	newIDs := map[string]int{}
+	var undone []JournalEntry
+	for i := len(entries) - 1; i >= len(entries)-n; i-- {
+		entry := entries[i]
+		if err = s.undo(entry, newIDs); err != nil {
+			err = fmt.Errorf("Error undoing %s of %s %d: %v", entry.Action, entry.Kind, entry.ID, err)
+			break
+		}
+		undone = append(undone, entry)
+	}
+
+	if session.IsDryRun() {
+		return undone, err
+	}
+	if terr := j.truncate(len(undone)); terr != nil && err == nil {
+		err = terr
+	}
+	return undone, err
+}
+
+func (session *Session) undo(entry JournalEntry, newIDs map[string]int) error {
+	key := func(id int) string {
+		return fmt.Sprintf("%s/%d", entry.Kind, id)
+	}
+	id := entry.ID
+	if newID, ok := newIDs[key(id)]; ok {
+		id = newID
+	}
+	rt := kindResourceTypes[entry.Kind]
+
+	switch entry.Action {
+	case "create":
+		_, err := session.delete(TogglAPI, generateResourceURLWithID(rt, entry.Wid, id))
+		return err
+
+	case "update":
+		var before map[string]interface{}
+		if err := json.Unmarshal(entry.Before, &before); err != nil {
+			return err
+		}
+		before["id"] = id
+		_, err := session.put(TogglAPI, generateResourceURLWithID(rt, entry.Wid, id), before)
+		return err
+
+	default:
+		var created []byte
+		var err error
+		if entry.Kind == "time_entries" {
+			var te TimeEntry
+			if err = json.Unmarshal(entry.Before, &te); err != nil {
+				return err
+			}
+			var recreated TimeEntry
+			recreated, err = session.CreateTimeEntry(te)
+			created, _ = json.Marshal(recreated)
+		} else {
+			var before map[string]interface{}
+			if err = json.Unmarshal(entry.Before, &before); err != nil {
+				return err
+			}
+			delete(before, "id")
+			created, err = session.post(TogglAPI, generateResourceURL(rt, entry.Wid), before)
+		}
+		if err != nil {
+			return err
+		}
+
+		var result struct {
+			ID int `json:"id"`
+		}
+		if json.Unmarshal(created, &result) == nil && result.ID != 0 {
+			newIDs[key(entry.ID)] = result.ID
+		}
+		return nil
+	}
+}
@@ -0,0 +1,188 @@
+package toggl
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+)
+
+// newJournaledSession returns a test session with a journal in a temporary
+// directory.
+func newJournaledSession(t *testing.T, entries ...TimeEntry) (*Session, *testAPI, *Journal) {
+	t.Helper()
+	session, api := newTestSession(t, entries...)
+	j, err := OpenJournal(filepath.Join(t.TempDir(), "journal"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	session.SetJournal(j)
+	return session, api, j
+}
+
+func journalLength(t *testing.T, j *Journal) int {
+	t.Helper()
+	entries, err := j.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(entries)
+}
+
+func TestUndo(t *testing.T) {
+	first := testEntry(1, "Reviews", testTime(9, 0), testTime(10, 0))
+	second := testEntry(2, "Deploy", testTime(10, 0), testTime(11, 0))
+	session, api, j := newJournaledSession(t, first, second)
+
+	changed := first
+	changed.Description = "Code reviews"
+	if _, err := session.UpdateTimeEntry(changed); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := session.CreateTimeEntry(testEntry(0, "Lunch", testTime(12, 0), testTime(13, 0))); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := session.DeleteTimeEntry(second); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := j.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 || entries[1].ID != 100 || entries[2].Before == nil {
+		t.Fatalf("unexpected journal %+v", entries)
+	}
+
+	undone, err := session.Undo(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(undone) != 3 || undone[0].Action != "delete" || undone[1].Action != "create" || undone[2].Action != "update" {
+		t.Errorf("unexpected undone entries %+v", undone)
+	}
+
+	checkRequests(t, api.sent()[3:], []string{
+		"POST /workspaces/1/time_entries",
+		"DELETE /workspaces/1/time_entries/100",
+		"PUT /workspaces/1/time_entries/1",
+	})
+
+	if ids := api.ids(); len(ids) != 2 || ids[0] != 1 || ids[1] != 101 {
+		t.Fatalf("entries are %v, want [1 101]", ids)
+	}
+	if e, _ := api.entry(1); e.Description != "Reviews" {
+		t.Errorf("update wasn't reverted: %+v", e)
+	}
+	if e, _ := api.entry(101); e.Description != "Deploy" || !e.Start.Equal(*testTime(10, 0)) || !e.Stop.Equal(*testTime(11, 0)) {
+		t.Errorf("deleted entry wasn't recreated: %+v", e)
+	}
+
+	// undoing isn't journaled, and the undone changes are removed
+	if n := journalLength(t, j); n != 0 {
+		t.Errorf("journal has %d entries", n)
+	}
+}
+
+func TestUndoRecreatedIDs(t *testing.T) {
+	entry := testEntry(1, "Reviews", testTime(9, 0), testTime(10, 0))
+	session, api, _ := newJournaledSession(t, entry)
+
+	changed := entry
+	changed.Description = "Code reviews"
+	if _, err := session.UpdateTimeEntry(changed); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := session.DeleteTimeEntry(changed); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := session.Undo(2); err != nil {
+		t.Fatal(err)
+	}
+
+	// the update is reverted on the entry recreated in its place
+	checkRequests(t, api.sent()[2:], []string{
+		"POST /workspaces/1/time_entries",
+		"PUT /workspaces/1/time_entries/100",
+	})
+	if ids := api.ids(); len(ids) != 1 || ids[0] != 100 {
+		t.Fatalf("entries are %v, want [100]", ids)
+	}
+	if e, _ := api.entry(100); e.Description != "Reviews" {
+		t.Errorf("update wasn't reverted: %+v", e)
+	}
+}
+
+func TestUndoFailure(t *testing.T) {
+	entry := testEntry(1, "Reviews", testTime(9, 0), testTime(10, 0))
+	session, api, j := newJournaledSession(t, entry)
+
+	if _, err := session.CreateTimeEntry(testEntry(0, "Lunch", testTime(12, 0), testTime(13, 0))); err != nil {
+		t.Fatal(err)
+	}
+	changed := entry
+	changed.Description = "Code reviews"
+	if _, err := session.UpdateTimeEntry(changed); err != nil {
+		t.Fatal(err)
+	}
+
+	// the update is reverted, but the created entry can't be deleted
+	api.fail("DELETE /workspaces/1/time_entries/100", http.StatusInternalServerError)
+	undone, err := session.Undo(5)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if len(undone) != 1 || undone[0].Action != "update" {
+		t.Errorf("unexpected undone entries %+v", undone)
+	}
+	if e, _ := api.entry(1); e.Description != "Reviews" {
+		t.Errorf("update wasn't reverted: %+v", e)
+	}
+
+	entries, err := j.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Action != "create" {
+		t.Errorf("journal has %+v, want the create", entries)
+	}
+}
+
+func TestUndoDryRun(t *testing.T) {
+	entry := testEntry(1, "Reviews", testTime(9, 0), testTime(10, 0))
+	session, api, j := newJournaledSession(t, entry)
+
+	if _, err := session.DeleteTimeEntry(entry); err != nil {
+		t.Fatal(err)
+	}
+
+	session.SetDryRun(true)
+	undone, err := session.Undo(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(undone) != 1 || undone[0].Action != "delete" {
+		t.Errorf("unexpected undone entries %+v", undone)
+	}
+
+	plan := session.DryRunPlan()
+	if len(plan) != 1 || plan[0].Method != "POST" {
+		t.Errorf("unexpected plan %+v", plan)
+	}
+	checkRequests(t, api.sent(), []string{"DELETE /workspaces/1/time_entries/1"})
+	if n := journalLength(t, j); n != 1 {
+		t.Errorf("journal has %d entries, want 1", n)
+	}
+
+	// the change can still be undone for real
+	session.SetDryRun(false)
+	if _, err := session.Undo(1); err != nil {
+		t.Fatal(err)
+	}
+	if n := journalLength(t, j); n != 0 {
+		t.Errorf("journal has %d entries after undoing", n)
+	}
+	if ids := api.ids(); len(ids) != 1 || ids[0] != 100 {
+		t.Errorf("entries are %v, want [100]", ids)
+	}
+}
@@ -0,0 +1,317 @@
+/*
+Package lint finds problems in lists of time entries, such as overlapping
+entries, untracked time during working hours, and entries without a project.
+*/
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jason0x43/go-toggl"
+)
+
+// Kind is a type of problem.
+type Kind int
+
+// Kinds of problems
+const (
+	Overlap Kind = iota
+	Gap
+	TooLong
+	SpansMidnight
+	ZeroDuration
+	MissingProject
+	MissingTags
+)
+
+var kindNames = map[Kind]string{
+	Overlap:        "overlap",
+	Gap:            "gap",
+	TooLong:        "too-long",
+	SpansMidnight:  "midnight",
+	ZeroDuration:   "zero",
+	MissingProject: "no-project",
+	MissingTags:    "no-tags",
+}
+
+func (k Kind) String() string {
+	return kindNames[k]
+}
+
+// Finding is a problem with one or more time entries, or with the time
+// between them.
+type Finding struct {
+	Kind Kind
+
+	// Entries are the IDs of the entries involved. Gaps involve no entries.
+	Entries []int
+
+	// Start and End bound the problem, such as the overlapping or untracked
+	// time.
+	Start time.Time
+	End   time.Time
+
+	Message string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s %s: %s", f.Start.Format("2006-01-02 15:04"), f.Kind, f.Message)
+}
+
+// Config controls which problems are reported.
+type Config struct {
+	// Location is the time zone used to determine days and working hours.
+	Location *time.Location
+
+	// WorkStart and WorkEnd are the start and end of working hours, as
+	// offsets from midnight. Gaps are only reported within working hours.
+	WorkStart time.Duration
+	WorkEnd   time.Duration
+
+	// WorkDays are the days gaps are reported on.
+	WorkDays []time.Weekday
+
+	// MinGap is the shortest untracked time that's reported. Gap detection is
+	// disabled if it's zero.
+	MinGap time.Duration
+
+	// MaxDuration is the longest an entry may be. Long entry detection is
+	// disabled if it's zero.
+	MaxDuration time.Duration
+
+	// Start and End optionally bound the checked range. When set, working
+	// days in the range without any entries are reported as gaps; otherwise
+	// only days with entries are checked for gaps.
+	Start time.Time
+	End   time.Time
+
+	RequireProject bool
+	RequireTags    bool
+
+	// Now is the time running entries are measured to, and after which gaps
+	// aren't reported. It defaults to the current time.
+	Now time.Time
+}
+
+// DefaultConfig returns a configuration for a 9 to 5 work week that reports
+// gaps of 15 minutes or more, entries longer than 10 hours, and entries
+// without a project.
+func DefaultConfig() Config {
+	return Config{
+		Location:  time.Local,
+		WorkStart: 9 * time.Hour,
+		WorkEnd:   17 * time.Hour,
+		WorkDays: []time.Weekday{
+			time.Monday,
+			time.Tuesday,
+			time.Wednesday,
+			time.Thursday,
+			time.Friday,
+		},
+		MinGap:         15 * time.Minute,
+		MaxDuration:    10 * time.Hour,
+		RequireProject: true,
+	}
+}
+
+// interval is a time entry's extent.
+type interval struct {
+	entry *toggl.TimeEntry
+	start time.Time
+	end   time.Time
+}
+
+// Check returns the problems found in a list of time entries, ordered by
+// start time.
+func Check(entries []toggl.TimeEntry, cfg Config) []Finding {
+	if cfg.Location == nil {
+		cfg.Location = time.Local
+	}
+	if cfg.Now.IsZero() {
+		cfg.Now = time.Now()
+	}
+
+	var intervals []interval
+	var findings []Finding
+
+	for i := range entries {
+		e := &entries[i]
+		if e.Start == nil {
+			continue
+		}
+
+		iv := interval{entry: e, start: *e.Start}
+		if e.IsRunning() {
+			iv.end = cfg.Now
+		} else {
+			iv.end = e.Start.Add(time.Duration(e.Duration) * time.Second)
+		}
+		intervals = append(intervals, iv)
+
+		findings = append(findings, checkEntry(iv, cfg)...)
+	}
+
+	sort.Slice(intervals, func(i, j int) bool {
+		return intervals[i].start.Before(intervals[j].start)
+	})
+
+	findings = append(findings, overlaps(intervals)...)
+	if cfg.MinGap > 0 {
+		findings = append(findings, gaps(intervals, cfg)...)
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Start.Before(findings[j].Start)
+	})
+	return findings
+}
+
+func checkEntry(iv interval, cfg Config) []Finding {
+	var findings []Finding
+	e := iv.entry
+	add := func(kind Kind, message string, args ...interface{}) {
+		findings = append(findings, Finding{
+			Kind:    kind,
+			Entries: []int{e.ID},
+			Start:   iv.start,
+			End:     iv.end,
+			Message: fmt.Sprintf("%q ", e.Description) + fmt.Sprintf(message, args...),
+		})
+	}
+
+	duration := iv.end.Sub(iv.start)
+	if !e.IsRunning() && duration <= 0 {
+		add(ZeroDuration, "has no duration")
+	}
+	if cfg.MaxDuration > 0 && duration > cfg.MaxDuration {
+		add(TooLong, "lasts %s", duration.Round(time.Minute))
+	}
+
+	startDay := iv.start.In(cfg.Location).Format("2006-01-02")
+	lastDay := iv.end.Add(-time.Nanosecond).In(cfg.Location).Format("2006-01-02")
+	if duration > 0 && startDay != lastDay {
+		add(SpansMidnight, "runs from %s to %s", startDay, lastDay)
+	}
+
+	if cfg.RequireProject && e.Pid == nil {
+		add(MissingProject, "has no project")
+	}
+	if cfg.RequireTags && len(e.Tags) == 0 {
+		add(MissingTags, "has no tags")
+	}
+
+	return findings
+}
+
+// overlaps finds overlapping entries in a list sorted by start time.
+func overlaps(intervals []interval) []Finding {
+	var findings []Finding
+	var latest *interval
+
+	for i := range intervals {
+		iv := &intervals[i]
+		if latest != nil && iv.start.Before(latest.end) {
+			end := iv.end
+			if latest.end.Before(end) {
+				end = latest.end
+			}
+			findings = append(findings, Finding{
+				Kind:    Overlap,
+				Entries: []int{latest.entry.ID, iv.entry.ID},
+				Start:   iv.start,
+				End:     end,
+				Message: fmt.Sprintf(
+					"%q overlaps %q by %s",
+					iv.entry.Description,
+					latest.entry.Description,
+					end.Sub(iv.start).Round(time.Second),
+				),
+			})
+		}
+		if latest == nil || iv.end.After(latest.end) {
+			latest = iv
+		}
+	}
+
+	return findings
+}
+
+// gaps finds untracked time during working hours.
+func gaps(intervals []interval, cfg Config) []Finding {
+	days := map[string]time.Time{}
+	addDay := func(t time.Time) {
+		t = t.In(cfg.Location)
+		y, m, d := t.Date()
+		day := time.Date(y, m, d, 0, 0, 0, 0, cfg.Location)
+		days[day.Format("2006-01-02")] = day
+	}
+
+	for _, iv := range intervals {
+		for t := iv.start; t.Before(iv.end); t = t.Add(24 * time.Hour) {
+			addDay(t)
+		}
+		addDay(iv.end)
+	}
+	if !cfg.Start.IsZero() && !cfg.End.IsZero() {
+		for t := cfg.Start; t.Before(cfg.End); t = t.AddDate(0, 0, 1) {
+			addDay(t)
+		}
+	}
+
+	var findings []Finding
+	for _, day := range days {
+		if !isWorkDay(day.Weekday(), cfg.WorkDays) {
+			continue
+		}
+
+		workStart := day.Add(cfg.WorkStart)
+		workEnd := day.Add(cfg.WorkEnd)
+		if !cfg.Start.IsZero() && workStart.Before(cfg.Start) {
+			workStart = cfg.Start
+		}
+		if !cfg.End.IsZero() && workEnd.After(cfg.End) {
+			workEnd = cfg.End
+		}
+		if workEnd.After(cfg.Now) {
+			workEnd = cfg.Now
+		}
+
+		cursor := workStart
+		report := func(end time.Time) {
+			if end.Sub(cursor) >= cfg.MinGap {
+				findings = append(findings, Finding{
+					Kind:    Gap,
+					Start:   cursor,
+					End:     end,
+					Message: fmt.Sprintf("%s untracked until %s", end.Sub(cursor), end.In(cfg.Location).Format("15:04")),
+				})
+			}
+		}
+
+		for _, iv := range intervals {
+			if !iv.end.After(cursor) || !iv.start.Before(workEnd) {
+				continue
+			}
+			if iv.start.After(cursor) {
+				report(iv.start)
+			}
+			cursor = iv.end
+		}
+		if cursor.Before(workEnd) {
+			report(workEnd)
+		}
+	}
+
+	return findings
+}
+
+func isWorkDay(day time.Weekday, workDays []time.Weekday) bool {
+	for _, d := range workDays {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
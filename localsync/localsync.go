@@ -0,0 +1,652 @@
+/*
+Package localsync mirrors a user's Toggl data into a local SQLite database.
+
+Workspaces, clients, projects, tags and tasks are replaced wholesale on every
+pull. Time entries are fetched incrementally using the API's "since" parameter,
+with deleted entries removed locally based on their server_deleted_at
+tombstones. A deletion of an entry with unpushed local edits is handled like
+any other conflicting edit. Local edits to time entries and locally created
+projects can be pushed back to Toggl.
+*/
+package localsync
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jason0x43/go-toggl"
+
+	// registers the "sqlite" database/sql driver
+	_ "modernc.org/sqlite"
+)
+
+// ConflictPolicy determines how conflicting local and remote edits to a time
+// entry are handled.
+type ConflictPolicy int
+
+const (
+	// LastWriterWins keeps whichever version of an entry was modified most
+	// recently.
+	LastWriterWins ConflictPolicy = iota
+
+	// Manual records conflicts and leaves both versions untouched until
+	// Resolve is called.
+	Manual
+)
+
+// DefaultInitialSync is how far back the first pull of a new store will
+// fetch time entries.
+const DefaultInitialSync = 90 * 24 * time.Hour
+
+// Store is a local mirror of a user's Toggl data.
+type Store struct {
+	Session     *toggl.Session
+	Policy      ConflictPolicy
+	InitialSync time.Duration
+
+	db *sql.DB
+}
+
+// Conflict describes a time entry that was modified both locally and
+// remotely since the last sync.
+type Conflict struct {
+	ID     int
+	Local  toggl.TimeEntry
+	Remote toggl.TimeEntry
+}
+
+// Result summarizes the changes made by a sync operation.
+type Result struct {
+	Updated   int
+	Deleted   int
+	Pushed    int
+	Conflicts int
+}
+
+const schema = `
+CREATE TABLE IF NOT EXISTS workspaces (
+	id INTEGER PRIMARY KEY,
+	data TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS clients (
+	id INTEGER PRIMARY KEY,
+	wid INTEGER NOT NULL,
+	name TEXT NOT NULL,
+	data TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS projects (
+	id INTEGER PRIMARY KEY,
+	wid INTEGER NOT NULL,
+	name TEXT NOT NULL,
+	data TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS pending_projects (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	wid INTEGER NOT NULL,
+	name TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS tags (
+	id INTEGER PRIMARY KEY,
+	wid INTEGER NOT NULL,
+	name TEXT NOT NULL,
+	data TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS tasks (
+	id INTEGER PRIMARY KEY,
+	pid INTEGER NOT NULL,
+	name TEXT NOT NULL,
+	data TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS time_entries (
+	id INTEGER PRIMARY KEY,
+	wid INTEGER NOT NULL,
+	pid INTEGER,
+	start TEXT,
+	data TEXT NOT NULL,
+	dirty INTEGER NOT NULL DEFAULT 0,
+	modified TEXT
+);
+CREATE INDEX IF NOT EXISTS time_entries_start ON time_entries (start);
+CREATE TABLE IF NOT EXISTS conflicts (
+	id INTEGER PRIMARY KEY,
+	remote TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS recreate (
+	id INTEGER PRIMARY KEY
+);
+CREATE TABLE IF NOT EXISTS sync_state (
+	key TEXT PRIMARY KEY,
+	value TEXT NOT NULL
+);
+`
+
+const cursorKey = "time_entries_since"
+
+// Open opens or creates a store in the SQLite database at path.
+func Open(path string, session *toggl.Session) (*Store, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("Error initializing database: %v", err)
+	}
+
+	return &Store{Session: session, InitialSync: DefaultInitialSync, db: db}, nil
+}
+
+// Close closes the underlying database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// DB returns the underlying database for read-only queries.
+func (s *Store) DB() *sql.DB {
+	return s.db
+}
+
+// Sync pulls remote changes and then pushes local ones.
+func (s *Store) Sync() (Result, error) {
+	pulled, err := s.Pull()
+	if err != nil {
+		return pulled, err
+	}
+
+	pushed, err := s.Push()
+	pulled.Pushed = pushed.Pushed
+	return pulled, err
+}
+
+// Cursor returns the time up to which time entries have been pulled. It is
+// the zero time if the store has never been synced.
+func (s *Store) Cursor() (time.Time, error) {
+	var value string
+	err := s.db.QueryRow(`SELECT value FROM sync_state WHERE key = ?`, cursorKey).Scan(&value)
+	if err == sql.ErrNoRows {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// Pull fetches remote changes into the store.
+func (s *Store) Pull() (result Result, err error) {
+	cursor, err := s.Cursor()
+	if err != nil {
+		return
+	}
+
+	// Use the time before the requests are made as the next cursor so that
+	// nothing modified while the sync is running can be missed.
+	now := time.Now()
+
+	account, err := s.Session.GetAccount()
+	if err != nil {
+		return
+	}
+
+	var entries []toggl.TimeEntry
+	if cursor.IsZero() {
+		entries, err = s.Session.GetTimeEntries(now.Add(-s.InitialSync), now)
+	} else {
+		entries, err = s.Session.GetTimeEntriesSince(cursor)
+	}
+	if err != nil {
+		return
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	if err = replaceMetadata(tx, account); err != nil {
+		return
+	}
+
+	for _, remote := range entries {
+		var keepLocal bool
+		if keepLocal, err = s.checkConflict(tx, remote); err != nil {
+			return
+		}
+		if keepLocal {
+			// a local edit that outlives a remote delete can only be pushed
+			// by creating the entry again
+			if remote.ServerDeletedAt != nil && s.Policy != Manual {
+				if _, err = tx.Exec(`INSERT OR REPLACE INTO recreate (id) VALUES (?)`, remote.ID); err != nil {
+					return
+				}
+			}
+			result.Conflicts++
+			continue
+		}
+
+		if remote.ServerDeletedAt != nil {
+			if err = deleteTimeEntry(tx, remote.ID); err != nil {
+				return
+			}
+			result.Deleted++
+			continue
+		}
+
+		if err = putTimeEntry(tx, remote, false); err != nil {
+			return
+		}
+		result.Updated++
+	}
+
+	_, err = tx.Exec(
+		`INSERT OR REPLACE INTO sync_state (key, value) VALUES (?, ?)`,
+		cursorKey,
+		now.UTC().Format(time.RFC3339),
+	)
+	return
+}
+
+// checkConflict determines whether a locally modified entry should be kept
+// instead of the given remote version, which may be a deletion. Under the
+// Manual policy a conflict is recorded.
+func (s *Store) checkConflict(tx *sql.Tx, remote toggl.TimeEntry) (bool, error) {
+	var modified sql.NullString
+	err := tx.QueryRow(
+		`SELECT modified FROM time_entries WHERE id = ? AND dirty = 1`,
+		remote.ID,
+	).Scan(&modified)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if s.Policy == Manual {
+		data, err := json.Marshal(remote)
+		if err != nil {
+			return false, err
+		}
+		_, err = tx.Exec(
+			`INSERT OR REPLACE INTO conflicts (id, remote) VALUES (?, ?)`,
+			remote.ID,
+			string(data),
+		)
+		return true, err
+	}
+
+	remoteTime := remote.At
+	if remote.ServerDeletedAt != nil {
+		remoteTime = remote.ServerDeletedAt
+	}
+	localTime, _ := time.Parse(time.RFC3339Nano, modified.String)
+	if remoteTime == nil || localTime.After(*remoteTime) {
+		return true, nil
+	}
+	return false, nil
+}
+
+// Push sends locally created projects and locally modified time entries to
+// Toggl. Entries with unresolved conflicts are skipped, and modified entries
+// that were deleted remotely are created again.
+func (s *Store) Push() (result Result, err error) {
+	if err = s.pushProjects(); err != nil {
+		return
+	}
+
+	rows, err := s.db.Query(
+		`SELECT data FROM time_entries
+		WHERE dirty = 1 AND id NOT IN (SELECT id FROM conflicts)`,
+	)
+	if err != nil {
+		return
+	}
+	dirty, err := scanTimeEntries(rows)
+	if err != nil {
+		return
+	}
+
+	for _, entry := range dirty {
+		var recreate bool
+		if recreate, err = s.recreating(entry.ID); err != nil {
+			return
+		}
+
+		var updated toggl.TimeEntry
+		if recreate {
+			updated, err = s.Session.CreateTimeEntry(entry)
+		} else {
+			updated, err = s.Session.UpdateTimeEntry(entry)
+		}
+		if err != nil {
+			return result, fmt.Errorf("Error pushing time entry %d: %v", entry.ID, err)
+		}
+		if recreate {
+			if err = deleteTimeEntry(s.db, entry.ID); err != nil {
+				return
+			}
+		}
+		if err = putTimeEntry(s.db, updated, false); err != nil {
+			return
+		}
+		result.Pushed++
+	}
+
+	return
+}
+
+// recreating reports whether a time entry was deleted remotely and should be
+// created again.
+func (s *Store) recreating(id int) (bool, error) {
+	err := s.db.QueryRow(`SELECT id FROM recreate WHERE id = ?`, id).Scan(&id)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// pushProjects creates pending local projects in Toggl and rewrites any local
+// time entries that refer to them.
+func (s *Store) pushProjects() error {
+	rows, err := s.db.Query(`SELECT id, wid, name FROM pending_projects`)
+	if err != nil {
+		return err
+	}
+
+	type pending struct {
+		id   int
+		wid  int
+		name string
+	}
+	var list []pending
+	for rows.Next() {
+		var p pending
+		if err := rows.Scan(&p.id, &p.wid, &p.name); err != nil {
+			rows.Close()
+			return err
+		}
+		list = append(list, p)
+	}
+	rows.Close()
+
+	for _, p := range list {
+		project, err := s.Session.CreateProject(p.name, p.wid)
+		if err != nil {
+			return fmt.Errorf("Error pushing project %q: %v", p.name, err)
+		}
+		if err := putProject(s.db, project); err != nil {
+			return err
+		}
+
+		entries, err := s.queryTimeEntries(`WHERE pid = ?`, -p.id)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			entry.Pid = &project.ID
+			if err := putTimeEntry(s.db, entry, true); err != nil {
+				return err
+			}
+		}
+
+		if _, err := s.db.Exec(`DELETE FROM pending_projects WHERE id = ?`, p.id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CreateProject records a new project locally. It will be created in Toggl on
+// the next push. The returned project has a negative ID that may be used for
+// local time entries until then.
+func (s *Store) CreateProject(name string, wid int) (toggl.Project, error) {
+	res, err := s.db.Exec(`INSERT INTO pending_projects (wid, name) VALUES (?, ?)`, wid, name)
+	if err != nil {
+		return toggl.Project{}, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return toggl.Project{}, err
+	}
+	return toggl.Project{ID: -int(id), Wid: wid, Name: name, Active: true}, nil
+}
+
+// UpdateTimeEntry stores a local modification to a time entry. It will be
+// sent to Toggl on the next push.
+func (s *Store) UpdateTimeEntry(entry toggl.TimeEntry) error {
+	return putTimeEntry(s.db, entry, true)
+}
+
+// TimeEntries returns the locally stored time entries that started within a
+// given range, ordered by start time.
+func (s *Store) TimeEntries(start, end time.Time) ([]toggl.TimeEntry, error) {
+	return s.queryTimeEntries(
+		`WHERE start >= ? AND start < ? ORDER BY start`,
+		start.UTC().Format(time.RFC3339Nano),
+		end.UTC().Format(time.RFC3339Nano),
+	)
+}
+
+// Projects returns the locally stored projects.
+func (s *Store) Projects() ([]toggl.Project, error) {
+	rows, err := s.db.Query(`SELECT data FROM projects ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []toggl.Project
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var project toggl.Project
+		if err := json.Unmarshal([]byte(data), &project); err != nil {
+			return nil, err
+		}
+		list = append(list, project)
+	}
+	return list, rows.Err()
+}
+
+// Conflicts returns the unresolved conflicts recorded under the Manual
+// policy. A conflict with a remote deletion has a Remote entry with a non-nil
+// ServerDeletedAt.
+func (s *Store) Conflicts() ([]Conflict, error) {
+	rows, err := s.db.Query(
+		`SELECT c.id, c.remote, e.data FROM conflicts c
+		JOIN time_entries e ON e.id = c.id`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []Conflict
+	for rows.Next() {
+		var c Conflict
+		var remote, local string
+		if err := rows.Scan(&c.ID, &remote, &local); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(remote), &c.Remote); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(local), &c.Local); err != nil {
+			return nil, err
+		}
+		list = append(list, c)
+	}
+	return list, rows.Err()
+}
+
+// Resolve resolves a conflict. If keepLocal is true the local version will be
+// pushed on the next sync, and created again if it was deleted remotely;
+// otherwise the remote version replaces it.
+func (s *Store) Resolve(id int, keepLocal bool) error {
+	var remote string
+	err := s.db.QueryRow(`SELECT remote FROM conflicts WHERE id = ?`, id).Scan(&remote)
+	if err != nil {
+		return err
+	}
+	var entry toggl.TimeEntry
+	if err := json.Unmarshal([]byte(remote), &entry); err != nil {
+		return err
+	}
+
+	switch {
+	case keepLocal && entry.ServerDeletedAt != nil:
+		_, err = s.db.Exec(`INSERT OR REPLACE INTO recreate (id) VALUES (?)`, id)
+	case keepLocal:
+	case entry.ServerDeletedAt != nil:
+		err = deleteTimeEntry(s.db, id)
+	default:
+		err = putTimeEntry(s.db, entry, false)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`DELETE FROM conflicts WHERE id = ?`, id)
+	return err
+}
+
+func (s *Store) queryTimeEntries(where string, args ...interface{}) ([]toggl.TimeEntry, error) {
+	rows, err := s.db.Query(`SELECT data FROM time_entries `+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanTimeEntries(rows)
+}
+
+// support /////////////////////////////////////////////////////////////
+
+// execer is implemented by both *sql.DB and *sql.Tx.
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+func scanTimeEntries(rows *sql.Rows) ([]toggl.TimeEntry, error) {
+	defer rows.Close()
+
+	var list []toggl.TimeEntry
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var entry toggl.TimeEntry
+		if err := json.Unmarshal([]byte(data), &entry); err != nil {
+			return nil, err
+		}
+		list = append(list, entry)
+	}
+	return list, rows.Err()
+}
+
+func putTimeEntry(db execer, entry toggl.TimeEntry, dirty bool) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	var start, modified interface{}
+	if entry.Start != nil {
+		start = entry.Start.UTC().Format(time.RFC3339Nano)
+	}
+	if dirty {
+		modified = time.Now().UTC().Format(time.RFC3339Nano)
+	}
+
+	_, err = db.Exec(
+		`INSERT OR REPLACE INTO time_entries (id, wid, pid, start, data, dirty, modified)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		entry.ID, entry.Wid, entry.Pid, start, string(data), dirty, modified,
+	)
+	return err
+}
+
+// deleteTimeEntry removes a time entry along with any conflict or pending
+// recreation recorded for it.
+func deleteTimeEntry(db execer, id int) error {
+	for _, table := range []string{"time_entries", "conflicts", "recreate"} {
+		if _, err := db.Exec(`DELETE FROM `+table+` WHERE id = ?`, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func putProject(db execer, project toggl.Project) error {
+	data, err := json.Marshal(project)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(
+		`INSERT OR REPLACE INTO projects (id, wid, name, data) VALUES (?, ?, ?, ?)`,
+		project.ID, project.Wid, project.Name, string(data),
+	)
+	return err
+}
+
+// replaceMetadata replaces all workspaces, clients, projects, tags and tasks
+// with the ones in the given account.
+func replaceMetadata(tx *sql.Tx, account toggl.Account) error {
+	for _, table := range []string{"workspaces", "clients", "projects", "tags", "tasks"} {
+		if _, err := tx.Exec(`DELETE FROM ` + table); err != nil {
+			return err
+		}
+	}
+
+	insert := func(query string, v interface{}, args ...interface{}) error {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(query, append(args, string(data))...)
+		return err
+	}
+
+	for _, w := range account.Workspaces {
+		if err := insert(`INSERT INTO workspaces (id, data) VALUES (?, ?)`, w, w.ID); err != nil {
+			return err
+		}
+	}
+	for _, c := range account.Clients {
+		err := insert(`INSERT INTO clients (id, wid, name, data) VALUES (?, ?, ?, ?)`, c, c.ID, c.Wid, c.Name)
+		if err != nil {
+			return err
+		}
+	}
+	for _, p := range account.Projects {
+		if err := putProject(tx, p); err != nil {
+			return err
+		}
+	}
+	for _, t := range account.Tags {
+		err := insert(`INSERT INTO tags (id, wid, name, data) VALUES (?, ?, ?, ?)`, t, t.ID, t.Wid, t.Name)
+		if err != nil {
+			return err
+		}
+	}
+	for _, t := range account.Tasks {
+		err := insert(`INSERT INTO tasks (id, pid, name, data) VALUES (?, ?, ?, ?)`, t, t.ID, t.Pid, t.Name)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
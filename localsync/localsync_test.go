@@ -0,0 +1,229 @@
+package localsync
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jason0x43/go-toggl"
+)
+
+// testServer is a Toggl API that returns a fixed list of time entries and
+// records the requests it receives.
+type testServer struct {
+	mu       sync.Mutex
+	entries  string
+	requests []string
+}
+
+func (ts *testServer) setEntries(entries string) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.entries = entries
+}
+
+func (ts *testServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.requests = append(ts.requests, r.Method+" "+r.URL.Path)
+
+	switch r.Method + " " + r.URL.Path {
+	case "GET /api/v9/me":
+		w.Write([]byte(`{"id":1,"workspaces":[{"id":1,"name":"Work"}]}`))
+	case "GET /api/v9/me/time_entries":
+		w.Write([]byte(ts.entries))
+	case "POST /api/v9/workspaces/1/time_entries":
+		w.Write([]byte(`{"id":2,"workspace_id":1,"description":"edited","start":"2026-10-18T09:00:00Z","duration":3600}`))
+	case "PUT /api/v9/workspaces/1/time_entries/1":
+		w.Write([]byte(`{"id":1,"workspace_id":1,"description":"edited","start":"2026-10-18T09:00:00Z","duration":3600}`))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// newTestStore opens a store in a temporary directory whose session talks to
+// a local server.
+func newTestStore(t *testing.T, policy ConflictPolicy) (*Store, *testServer) {
+	t.Helper()
+	toggl.DisableLog()
+
+	ts := &testServer{entries: "[]"}
+	server := httptest.NewServer(ts)
+	t.Cleanup(server.Close)
+
+	session := toggl.OpenSession("token")
+	session.Use(func(next toggl.RoundTripFunc) toggl.RoundTripFunc {
+		return func(req *toggl.Request) (*toggl.Response, error) {
+			req.API = strings.Replace(req.API, "https://api.track.toggl.com", server.URL, 1)
+			return next(req)
+		}
+	})
+
+	store, err := Open(filepath.Join(t.TempDir(), "toggl.db"), &session)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	store.Policy = policy
+	return store, ts
+}
+
+const testEntry = `{"id":1,"workspace_id":1,"description":"original","start":"2026-10-18T09:00:00Z","duration":3600,"at":"2026-10-18T10:00:00Z"}`
+
+// deletedEntry returns a tombstone for the test entry.
+func deletedEntry(at time.Time) string {
+	return fmt.Sprintf(
+		`[{"id":1,"workspace_id":1,"start":"2026-10-18T09:00:00Z","duration":3600,"at":%q,"server_deleted_at":%q}]`,
+		at.UTC().Format(time.RFC3339), at.UTC().Format(time.RFC3339),
+	)
+}
+
+// editLocally pulls the test entry and changes its description locally.
+func editLocally(t *testing.T, store *Store, ts *testServer) {
+	t.Helper()
+	ts.setEntries("[" + testEntry + "]")
+	if _, err := store.Pull(); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := store.TimeEntries(time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC), time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries after pull, want 1", len(entries))
+	}
+	entries[0].Description = "edited"
+	if err := store.UpdateTimeEntry(entries[0]); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func localEntries(t *testing.T, store *Store) []toggl.TimeEntry {
+	t.Helper()
+	entries, err := store.queryTimeEntries(`ORDER BY id`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entries
+}
+
+func TestPullDeleteConflictManual(t *testing.T) {
+	store, ts := newTestStore(t, Manual)
+	editLocally(t, store, ts)
+
+	ts.setEntries(deletedEntry(time.Now().Add(time.Hour)))
+	result, err := store.Pull()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Deleted != 0 || result.Conflicts != 1 {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	entries := localEntries(t, store)
+	if len(entries) != 1 || entries[0].Description != "edited" {
+		t.Fatalf("local edit wasn't kept: %+v", entries)
+	}
+	conflicts, err := store.Conflicts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conflicts) != 1 || conflicts[0].Remote.ServerDeletedAt == nil {
+		t.Fatalf("unexpected conflicts %+v", conflicts)
+	}
+
+	// the conflict blocks pushing until it's resolved
+	if result, err = store.Push(); err != nil || result.Pushed != 0 {
+		t.Fatalf("pushed %d entries with an unresolved conflict: %v", result.Pushed, err)
+	}
+
+	if err := store.Resolve(1, true); err != nil {
+		t.Fatal(err)
+	}
+	if result, err = store.Push(); err != nil || result.Pushed != 1 {
+		t.Fatalf("pushed %d entries: %v", result.Pushed, err)
+	}
+	if got := ts.requests[len(ts.requests)-1]; got != "POST /api/v9/workspaces/1/time_entries" {
+		t.Errorf("entry was pushed with %s", got)
+	}
+	if entries := localEntries(t, store); len(entries) != 1 || entries[0].ID != 2 {
+		t.Errorf("recreated entry wasn't stored: %+v", entries)
+	}
+}
+
+func TestResolveDeleteConflictRemote(t *testing.T) {
+	store, ts := newTestStore(t, Manual)
+	editLocally(t, store, ts)
+
+	ts.setEntries(deletedEntry(time.Now().Add(time.Hour)))
+	if _, err := store.Pull(); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Resolve(1, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if entries := localEntries(t, store); len(entries) != 0 {
+		t.Errorf("deleted entry is still stored: %+v", entries)
+	}
+	if conflicts, _ := store.Conflicts(); len(conflicts) != 0 {
+		t.Errorf("conflict wasn't removed: %+v", conflicts)
+	}
+}
+
+func TestPullDeleteLastWriterWins(t *testing.T) {
+	tests := []struct {
+		name      string
+		edit      bool
+		deletedAt time.Duration
+		deleted   bool
+		method    string
+	}{
+		{"clean entry", false, time.Hour, true, ""},
+		{"delete after edit", true, time.Hour, true, ""},
+		{"edit after delete", true, -time.Hour, false, "POST"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store, ts := newTestStore(t, LastWriterWins)
+			if test.edit {
+				editLocally(t, store, ts)
+			} else {
+				ts.setEntries("[" + testEntry + "]")
+				if _, err := store.Pull(); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			ts.setEntries(deletedEntry(time.Now().Add(test.deletedAt)))
+			result, err := store.Pull()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if deleted := result.Deleted == 1; deleted != test.deleted {
+				t.Errorf("unexpected result %+v", result)
+			}
+			if entries := localEntries(t, store); (len(entries) == 0) != test.deleted {
+				t.Errorf("unexpected local entries %+v", entries)
+			}
+
+			result, err = store.Push()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if pushed := result.Pushed == 1; pushed != (test.method != "") {
+				t.Errorf("pushed %d entries", result.Pushed)
+			}
+			if test.method != "" && !strings.HasPrefix(ts.requests[len(ts.requests)-1], test.method) {
+				t.Errorf("entry was pushed with %s", ts.requests[len(ts.requests)-1])
+			}
+		})
+	}
+}
@@ -7,6 +7,7 @@ package toggl
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -14,6 +15,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"strconv"
 	"time"
 )
 
@@ -29,15 +31,17 @@ type resourceType int
 const (
 	clients resourceType = iota
 	projects
+	projectUsers
 	tags
 	timeEntries
 )
 
 var resourceTypeMap = map[resourceType]string{
-	clients:     "clients",
-	projects:    "projects",
-	tags:        "tags",
-	timeEntries: "time_entries",
+	clients:      "clients",
+	projects:     "projects",
+	projectUsers: "project_users",
+	tags:         "tags",
+	timeEntries:  "time_entries",
 }
 
 func (r resourceType) String() string {
@@ -71,11 +75,19 @@ type Session struct {
 	APIToken string
 	username string
 	password string
+	ctx      context.Context
+	dryRun   *dryRun
+	journal  *Journal
+
+	// Middleware wraps every request the session makes, outermost first.
+	Middleware []Middleware
 }
 
 // Account represents a user account.
 type Account struct {
 	APIToken        string      `json:"api_token"`
+	Email           string      `json:"email"`
+	Fullname        string      `json:"fullname"`
 	Timezone        string      `json:"timezone"`
 	ID              int         `json:"id"`
 	Workspaces      []Workspace `json:"workspaces"`
@@ -89,11 +101,13 @@ type Account struct {
 
 // Workspace represents a user workspace.
 type Workspace struct {
-	ID              int    `json:"id"`
-	RoundingMinutes int    `json:"rounding_minutes"`
-	Rounding        int    `json:"rounding"`
-	Name            string `json:"name"`
-	Premium         bool   `json:"premium"`
+	ID                int     `json:"id"`
+	RoundingMinutes   int     `json:"rounding_minutes"`
+	Rounding          int     `json:"rounding"`
+	Name              string  `json:"name"`
+	Premium           bool    `json:"premium"`
+	DefaultHourlyRate float64 `json:"default_hourly_rate"`
+	DefaultCurrency   string  `json:"default_currency"`
 }
 
 // Client represents a client.
@@ -113,6 +127,8 @@ type Project struct {
 	Name            string     `json:"name"`
 	Active          bool       `json:"active"`
 	Billable        *bool      `json:"billable,omitempty"`
+	Rate            *float64   `json:"rate,omitempty"`
+	Currency        *string    `json:"currency,omitempty"`
 	ServerDeletedAt *time.Time `json:"server_deleted_at,omitempty"`
 }
 
@@ -121,6 +137,16 @@ func (p *Project) IsActive() bool {
 	return p.Active && p.ServerDeletedAt == nil
 }
 
+// ProjectUser represents a user's membership in a project, including the
+// user's hourly rate for the project, if one is set.
+type ProjectUser struct {
+	Wid  int      `json:"workspace_id"`
+	Pid  int      `json:"project_id"`
+	Uid  int      `json:"user_id"`
+	ID   int      `json:"id"`
+	Rate *float64 `json:"rate,omitempty"`
+}
+
 // Task represents a task.
 type Task struct {
 	Wid  int    `json:"wid"`
@@ -142,6 +168,7 @@ type TimeEntry struct {
 	ID          int        `json:"id,omitempty"`
 	Pid         *int       `json:"project_id,omitempty"`
 	Tid         *int       `json:"task_id,omitempty"`
+	Uid         int        `json:"user_id,omitempty"`
 	Description string     `json:"description,omitempty"`
 	Stop        *time.Time `json:"stop,omitempty"`
 	Start       *time.Time `json:"start,omitempty"`
@@ -149,8 +176,17 @@ type TimeEntry struct {
 	Duration    int64      `json:"duration,omitempty"`
 	DurOnly     bool       `json:"duronly"`
 	Billable    bool       `json:"billable"`
+	At          *time.Time `json:"at,omitempty"`
+
+	ServerDeletedAt *time.Time `json:"server_deleted_at,omitempty"`
+
+	// The names of an entry's project and client, as returned with entries
+	// loaded by GetTimeEntries and GetTimeEntriesSince
+	ProjectName string `json:"project_name,omitempty"`
+	ClientName  string `json:"client_name,omitempty"`
 }
 
+// DetailedTimeEntry represents a time entry in a detailed report.
 type DetailedTimeEntry struct {
 	ID              int        `json:"id"`
 	Pid             int        `json:"pid"`
@@ -162,6 +198,7 @@ type DetailedTimeEntry struct {
 	ProjectColor    string     `json:"project_color"`
 	ProjectHexColor string     `json:"project_hex_color"`
 	Client          string     `json:"client"`
+	Task            string     `json:"task"`
 	Start           *time.Time `json:"start"`
 	End             *time.Time `json:"end"`
 	Updated         *time.Time `json:"updated"`
@@ -334,21 +371,74 @@ func newStartEntryRequestData(description string, workspaceId int) timeEntryCrea
 	}
 }
 
+// RunningTimerError is returned when starting a timer while another one is
+// already running. Use SwitchTimer to replace the running timer.
+type RunningTimerError struct {
+	Running TimeEntry
+}
+
+func (e *RunningTimerError) Error() string {
+	return fmt.Sprintf("time entry %d (%q) is already running", e.Running.ID, e.Running.Description)
+}
+
+// ResponseError is returned when Toggl responds to a request with an error
+// status.
+type ResponseError struct {
+	StatusCode int
+	Status     string
+	Header     http.Header
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("Response error: %s", e.Status)
+}
+
+// Temporary returns true if the request may succeed if it's made again later:
+// Toggl is rate limiting requests or has a server error.
+func (e *ResponseError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// RetryAfter returns how long Toggl asked to wait before another request, or
+// 0 if it didn't say.
+func (e *ResponseError) RetryAfter() time.Duration {
+	seconds, err := strconv.Atoi(e.Header.Get("Retry-After"))
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
 // startTimeEntry unified way how to start new entries. Eventually it should replace StartTimeEntry and
 // StartTimeEntryForProject functions, which are for time-being kept for compatibility.
+//
+// Running entries are only started if no other timer is running; otherwise a
+// *RunningTimerError is returned.
 func (session *Session) startTimeEntry(timeEntry timeEntryCreate) (TimeEntry, error) {
+	if timeEntry.Duration < 0 {
+		current, err := session.GetCurrentTimeEntry()
+		if err != nil {
+			return TimeEntry{}, fmt.Errorf("Error checking for a running timer: %v", err)
+		}
+		if current.ID != 0 {
+			return TimeEntry{}, &RunningTimerError{Running: current}
+		}
+	}
+
 	return handleTimeEntryResponse(
 		session.post(TogglAPI, generateResourceURL(timeEntries, timeEntry.WorkspaceId), timeEntry),
 	)
 }
 
-// StartTimeEntry creates a new time entry.
+// StartTimeEntry creates a new time entry. If another timer is already
+// running, a *RunningTimerError is returned.
 func (session *Session) StartTimeEntry(description string, wid int) (TimeEntry, error) {
 	return session.startTimeEntry(newStartEntryRequestData(description, wid))
 }
 
 // StartTimeEntryForProject creates a new time entry for a specific project. Note that the 'billable' option is only
-// meaningful for Toggl Pro accounts; it will be ignored for free accounts.
+// meaningful for Toggl Pro accounts; it will be ignored for free accounts. If another timer is already running, a
+// *RunningTimerError is returned.
 func (session *Session) StartTimeEntryForProject(
 	description string,
 	wid int,
@@ -365,6 +455,22 @@ func (session *Session) StartTimeEntryForProject(
 	return session.startTimeEntry(entry)
 }
 
+// CreateTimeEntry creates a new time entry with the start time, duration,
+// description and metadata of the given one. A stopped entry should have its
+// Stop time set; a negative Duration creates a running entry.
+func (session *Session) CreateTimeEntry(timeEntry TimeEntry) (TimeEntry, error) {
+	entry := timeEntryCreate{
+		Description: timeEntry.Description,
+		Duration:    int(timeEntry.Duration),
+		Start:       timeEntry.Start,
+		Stop:        timeEntry.Stop,
+		WorkspaceId: timeEntry.Wid,
+	}
+	entry = entry.withMetadataFromTimeEntry(timeEntry)
+
+	return session.startTimeEntry(entry)
+}
+
 // GetCurrentTimeEntry returns the current time entry, that's running
 func (session *Session) GetCurrentTimeEntry() (TimeEntry, error) {
 	return handleTimeEntryResponse(
@@ -380,6 +486,33 @@ func (session *Session) GetTimeEntries(startDate, endDate time.Time) ([]TimeEntr
 		map[string]string{
 			"start_date": startDate.Format(time.RFC3339),
 			"end_date":   endDate.Format(time.RFC3339),
+			"meta":       "true",
+		},
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var results []TimeEntry
+	err = json.Unmarshal(data, &results)
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// GetTimeEntriesSince returns the time entries that have been created,
+// updated or deleted since a given time. Deleted entries will have a non-nil
+// ServerDeletedAt.
+func (session *Session) GetTimeEntriesSince(since time.Time) ([]TimeEntry, error) {
+	data, err := session.get(
+		TogglAPI,
+		generateUserResourceURL(timeEntries),
+		map[string]string{
+			"since": fmt.Sprintf("%d", since.Unix()),
+			"meta":  "true",
 		},
 	)
 
@@ -425,7 +558,9 @@ func (session *Session) ContinueTimeEntry(timer TimeEntry, duronly bool) (TimeEn
 }
 
 // UnstopTimeEntry starts a new entry that is a copy of the given one, including
-// the given timer's start time. The given time entry is then deleted.
+// the given timer's start time. The given time entry is deleted only once the
+// new entry has been started. If the old entry can't be deleted, the new entry
+// is returned along with an error.
 func (session *Session) UnstopTimeEntry(timer TimeEntry) (newEntry TimeEntry, err error) {
 	dlog.Printf("Unstopping timer %v", timer)
 
@@ -433,7 +568,9 @@ func (session *Session) UnstopTimeEntry(timer TimeEntry) (newEntry TimeEntry, er
 	entry = entry.withMetadataFromTimeEntry(timer)
 	entry.Start = timer.Start
 
-	newEntry, err = session.startTimeEntry(entry)
+	if newEntry, err = session.startTimeEntry(entry); err != nil {
+		return TimeEntry{}, err
+	}
 	if _, err = session.DeleteTimeEntry(timer); err != nil {
 		err = fmt.Errorf("old entry not deleted: %v", err)
 	}
@@ -441,6 +578,45 @@ func (session *Session) UnstopTimeEntry(timer TimeEntry) (newEntry TimeEntry, er
 	return
 }
 
+// SwitchTimer stops the running timer, if there is one, and starts a new one
+// with the description, workspace, project, task, tags and billable flag of
+// next. The new timer starts when the old one stops. If the new timer can't
+// be started, the old one is restarted with its original start time.
+func (session *Session) SwitchTimer(next TimeEntry) (stopped, started TimeEntry, err error) {
+	dlog.Printf("Switching to timer %v", next)
+
+	current, err := session.GetCurrentTimeEntry()
+	if err != nil {
+		return stopped, started, fmt.Errorf("Error getting running timer: %v", err)
+	}
+
+	now := time.Now()
+	if current.ID != 0 {
+		if stopped, err = session.StopTimeEntry(current); err != nil {
+			return TimeEntry{}, started, fmt.Errorf("Error stopping running timer: %v", err)
+		}
+		if stopped.Stop != nil {
+			now = *stopped.Stop
+		}
+	}
+
+	entry := newStartEntryRequestData(next.Description, next.Wid)
+	entry = entry.withMetadataFromTimeEntry(next)
+	entry.Start = &now
+
+	if started, err = session.startTimeEntry(entry); err != nil {
+		err = fmt.Errorf("Error starting timer: %v", err)
+		if current.ID != 0 {
+			if _, rerr := session.UnstopTimeEntry(stopped); rerr != nil {
+				err = fmt.Errorf("%v; stopped timer not restarted: %v", err, rerr)
+			}
+		}
+		return TimeEntry{}, TimeEntry{}, err
+	}
+
+	return stopped, started, nil
+}
+
 // StopTimeEntry stops a running time entry.
 func (session *Session) StopTimeEntry(timer TimeEntry) (TimeEntry, error) {
 	dlog.Printf("Stopping timer %v", timer)
@@ -575,6 +751,23 @@ func (session *Session) DeleteProject(project Project) ([]byte, error) {
 	return session.delete(TogglAPI, generateResourceURLWithID(projects, project.Wid, project.ID))
 }
 
+// GetProjectUsers returns the project memberships in a workspace.
+func (session *Session) GetProjectUsers(wid int) ([]ProjectUser, error) {
+	dlog.Printf("Getting project users for workspace %d", wid)
+	data, err := session.get(TogglAPI, generateResourceURL(projectUsers, wid), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var list []ProjectUser
+	err = json.Unmarshal(data, &list)
+	if err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
 // CreateTag creates a new tag.
 func (session *Session) CreateTag(name string, wid int) (tag Tag, err error) {
 	dlog.Printf("Creating tag %s", name)
@@ -661,10 +854,12 @@ func (e *TimeEntry) Copy() TimeEntry {
 	newEntry.Tags = make([]string, len(e.Tags))
 	copy(newEntry.Tags, e.Tags)
 	if e.Start != nil {
-		newEntry.Start = &(*e.Start)
+		start := *e.Start
+		newEntry.Start = &start
 	}
 	if e.Stop != nil {
-		newEntry.Stop = &(*e.Stop)
+		stop := *e.Stop
+		newEntry.Stop = &stop
 	}
 	return newEntry
 }
@@ -775,8 +970,56 @@ func (e *TimeEntry) UnmarshalJSON(b []byte) error {
 
 // support /////////////////////////////////////////////////////////////
 
-func (session *Session) request(method string, requestURL string, body io.Reader) ([]byte, error) {
-	req, err := http.NewRequest(method, requestURL, body)
+// request sends a request through the session's middleware.
+func (session *Session) request(req *Request) ([]byte, error) {
+	if req.Header == nil {
+		req.Header = http.Header{}
+	}
+	req.Context = session.Context()
+	req.Operation = operationName()
+
+	roundTrip := session.send
+	if dryRun := session.dryRun; dryRun != nil {
+		roundTrip = func(req *Request) (*Response, error) {
+			if req.IsMutation() {
+				return dryRun.record(req)
+			}
+			return session.send(req)
+		}
+	} else if session.journal != nil {
+		roundTrip = session.journal.wrap(roundTrip)
+	}
+	for i := len(session.Middleware) - 1; i >= 0; i-- {
+		roundTrip = session.Middleware[i](roundTrip)
+	}
+
+	resp, err := roundTrip(req)
+	if resp == nil {
+		return nil, err
+	}
+	return resp.Body, err
+}
+
+// send makes a request to Toggl. It's the innermost RoundTripFunc of every
+// middleware chain.
+func (session *Session) send(r *Request) (*Response, error) {
+	var body io.Reader
+	if r.Body != nil {
+		data, err := json.Marshal(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		dlog.Printf("data: %s", data)
+		body = bytes.NewBuffer(data)
+	}
+
+	req, err := http.NewRequestWithContext(r.Context, r.Method, r.URL(), body)
+	if err != nil {
+		return nil, fmt.Errorf("Error making request: %v", err)
+	}
+	for key, values := range r.Header {
+		req.Header[key] = values
+	}
 
 	if session.APIToken != "" {
 		req.SetBasicAuth(session.APIToken, "api_token")
@@ -784,24 +1027,34 @@ func (session *Session) request(method string, requestURL string, body io.Reader
 		req.SetBasicAuth(session.username, session.password)
 	}
 
-	req.Header.Add("Content-Type", "application/json")
+	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := client.Do(req)
+	httpResp, err := client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("Error making request: %v", err)
 	}
-	defer resp.Body.Close()
+	defer httpResp.Body.Close()
 
-	content, err := io.ReadAll(resp.Body)
+	content, err := io.ReadAll(httpResp.Body)
 	if err != nil {
 		return nil, fmt.Errorf("Error reading body: %v", err)
 	}
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
-		return content, fmt.Errorf("Response error: %s", resp.Status)
+	resp := &Response{
+		StatusCode: httpResp.StatusCode,
+		Status:     httpResp.Status,
+		Header:     httpResp.Header,
+		Body:       content,
+	}
+	if httpResp.StatusCode < 200 || httpResp.StatusCode >= 400 {
+		return resp, &ResponseError{
+			StatusCode: httpResp.StatusCode,
+			Status:     httpResp.Status,
+			Header:     httpResp.Header,
+		}
 	}
 
-	return content, nil
+	return resp, nil
 }
 
 func (session *Session) get(
@@ -809,63 +1062,41 @@ func (session *Session) get(
 	path string,
 	params map[string]string,
 ) ([]byte, error) {
-	requestURL += path
+	req := &Request{Method: "GET", API: requestURL, Path: path}
 
 	if params != nil {
-		data := url.Values{}
+		req.Query = url.Values{}
 		for key, value := range params {
-			data.Set(key, value)
+			req.Query.Set(key, value)
 		}
-		requestURL += "?" + data.Encode()
 	}
 
-	dlog.Printf("GETing from URL: %s", requestURL)
-	return session.request("GET", requestURL, nil)
+	dlog.Printf("GETing from URL: %s", req.URL())
+	return session.request(req)
 }
 
 func (session *Session) post(requestURL string, path string, data interface{}) ([]byte, error) {
-	requestURL += path
-	var body []byte
-	var err error
-
-	if data != nil {
-		body, err = json.Marshal(data)
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	dlog.Printf("POSTing to URL: %s", requestURL)
-	dlog.Printf("data: %s", body)
-	return session.request("POST", requestURL, bytes.NewBuffer(body))
+	req := &Request{Method: "POST", API: requestURL, Path: path, Body: data}
+	dlog.Printf("POSTing to URL: %s", req.URL())
+	return session.request(req)
 }
 
 func (session *Session) put(requestURL string, path string, data interface{}) ([]byte, error) {
-	requestURL += path
-	var body []byte
-	var err error
-
-	if data != nil {
-		body, err = json.Marshal(data)
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	dlog.Printf("PUTing to URL %s: %s", requestURL, string(body))
-	return session.request("PUT", requestURL, bytes.NewBuffer(body))
+	req := &Request{Method: "PUT", API: requestURL, Path: path, Body: data}
+	dlog.Printf("PUTing to URL %s", req.URL())
+	return session.request(req)
 }
 
 func (session *Session) patch(requestURL string, path string) ([]byte, error) {
-	requestURL += path
-	dlog.Printf("PATCHing to URL %s", requestURL)
-	return session.request("PATCH", requestURL, nil)
+	req := &Request{Method: "PATCH", API: requestURL, Path: path}
+	dlog.Printf("PATCHing to URL %s", req.URL())
+	return session.request(req)
 }
 
 func (session *Session) delete(requestURL string, path string) ([]byte, error) {
-	requestURL += path
-	dlog.Printf("DELETINGing URL: %s", requestURL)
-	return session.request("DELETE", requestURL, nil)
+	req := &Request{Method: "DELETE", API: requestURL, Path: path}
+	dlog.Printf("DELETINGing URL: %s", req.URL())
+	return session.request(req)
 }
 
 func decodeSession(data []byte, session *Session) error {
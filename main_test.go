@@ -0,0 +1,223 @@
+package toggl
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testAPI is a small in-memory version of Toggl's time entry API.
+type testAPI struct {
+	mu       sync.Mutex
+	entries  map[int]TimeEntry
+	nextID   int
+	requests []string
+
+	// failures maps requests, such as "DELETE /workspaces/1/time_entries/2",
+	// to the status they fail with
+	failures map[string]int
+}
+
+var testEntryPath = regexp.MustCompile(`^/workspaces/(\d+)/time_entries(?:/(\d+))?$`)
+
+// newTestSession returns a session whose requests are sent to a testAPI
+// holding the given entries. Requests keep their Toggl URLs; the HTTP client
+// is replaced for the duration of the test.
+func newTestSession(t *testing.T, entries ...TimeEntry) (*Session, *testAPI) {
+	t.Helper()
+	DisableLog()
+
+	api := &testAPI{entries: map[int]TimeEntry{}, nextID: 100, failures: map[string]int{}}
+	for _, e := range entries {
+		api.entries[e.ID] = e.Copy()
+	}
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+
+	serverURL, _ := url.Parse(server.URL)
+	saved := client
+	client = &http.Client{Transport: testTransport{serverURL}}
+	t.Cleanup(func() { client = saved })
+
+	session := OpenSession("token")
+	return &session, api
+}
+
+// testTransport sends requests to a test server.
+type testTransport struct {
+	server *url.URL
+}
+
+func (tt testTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = tt.server.Scheme
+	req.URL.Host = tt.server.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func (api *testAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/api/v9")
+	request := r.Method + " " + path
+	api.requests = append(api.requests, request)
+	if status, ok := api.failures[request]; ok {
+		http.Error(w, "failed", status)
+		return
+	}
+
+	if path == "/me/time_entries" && r.Method == "GET" {
+		var list []TimeEntry
+		for _, e := range api.entries {
+			list = append(list, e)
+		}
+		sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+		json.NewEncoder(w).Encode(list)
+		return
+	}
+
+	if id, err := strconv.Atoi(strings.TrimPrefix(path, "/me/time_entries/")); err == nil && r.Method == "GET" {
+		entry, ok := api.entries[id]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(entry)
+		return
+	}
+
+	if path == "/me/time_entries/current" && r.Method == "GET" {
+		for _, e := range api.entries {
+			if e.IsRunning() {
+				json.NewEncoder(w).Encode(e)
+				return
+			}
+		}
+		w.Write([]byte("null"))
+		return
+	}
+
+	m := testEntryPath.FindStringSubmatch(path)
+	if m == nil {
+		http.NotFound(w, r)
+		return
+	}
+	wid, _ := strconv.Atoi(m[1])
+	id, _ := strconv.Atoi(m[2])
+
+	var entry TimeEntry
+	if r.Method == "POST" || r.Method == "PUT" {
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &entry); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		entry.Wid = wid
+		if entry.Stop == nil && entry.Start != nil && entry.Duration > 0 {
+			stop := entry.Start.Add(time.Duration(entry.Duration) * time.Second)
+			entry.Stop = &stop
+		}
+	}
+
+	switch {
+	case r.Method == "POST" && m[2] == "":
+		entry.ID = api.nextID
+		api.nextID++
+	case r.Method == "PUT" && api.has(id):
+		entry.ID = id
+	case r.Method == "DELETE" && api.has(id):
+		delete(api.entries, id)
+		return
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	api.entries[entry.ID] = entry
+	json.NewEncoder(w).Encode(entry)
+}
+
+func (api *testAPI) has(id int) bool {
+	_, ok := api.entries[id]
+	return ok
+}
+
+// fail makes a request fail with a status.
+func (api *testAPI) fail(request string, status int) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	api.failures[request] = status
+}
+
+// entry returns a stored entry.
+func (api *testAPI) entry(id int) (TimeEntry, bool) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	e, ok := api.entries[id]
+	return e, ok
+}
+
+// ids returns the IDs of the stored entries in order.
+func (api *testAPI) ids() []int {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	var ids []int
+	for id := range api.entries {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// sent returns the requests the API received, excluding reads.
+func (api *testAPI) sent() []string {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	var list []string
+	for _, r := range api.requests {
+		if !strings.HasPrefix(r, "GET ") {
+			list = append(list, r)
+		}
+	}
+	return list
+}
+
+// testTime returns a time on 16 October 2026 in UTC.
+func testTime(hour, min int) *time.Time {
+	t := time.Date(2026, 10, 16, hour, min, 0, 0, time.UTC)
+	return &t
+}
+
+// testEntry returns a stopped entry in workspace 1.
+func testEntry(id int, description string, start, stop *time.Time) TimeEntry {
+	pid := 7
+	return TimeEntry{
+		ID:          id,
+		Wid:         1,
+		Pid:         &pid,
+		Description: description,
+		Tags:        []string{"client"},
+		Billable:    true,
+		Start:       start,
+		Stop:        stop,
+		Duration:    int64(stop.Sub(*start) / time.Second),
+	}
+}
+
+func checkRequests(t *testing.T, got, want []string) {
+	t.Helper()
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("sent requests\n\t%v\nwant\n\t%v", strings.Join(got, "\n\t"), strings.Join(want, "\n\t"))
+	}
+}
@@ -0,0 +1,171 @@
+package toggl
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Request is a request to Toggl as seen by middleware. Middleware may change
+// any of its fields before passing it on.
+type Request struct {
+	// Context is the context of the session that made the request.
+	Context context.Context
+
+	// Operation names the Session method that made the request, such as
+	// "toggl.StartTimeEntry".
+	Operation string
+
+	Method string
+
+	// API is the base URL of the API the request is for, such as TogglAPI or
+	// ReportsAPI.
+	API string
+
+	// Path is the request's path relative to API.
+	Path string
+
+	Query url.Values
+
+	// Body is the value that will be sent as the request's JSON body, or nil.
+	Body interface{}
+
+	// Header holds extra headers to send. Authorization and Content-Type are
+	// set by the session.
+	Header http.Header
+
+	// Retries is the number of times the request has been resent. Middleware
+	// that retries requests, such as Retry, increments it before each new
+	// attempt.
+	Retries int
+}
+
+// URL returns the request's full URL.
+func (r *Request) URL() string {
+	u := r.API + r.Path
+	if len(r.Query) > 0 {
+		u += "?" + r.Query.Encode()
+	}
+	return u
+}
+
+// IsMutation returns true if the request changes data in Toggl.
+func (r *Request) IsMutation() bool {
+	return r.Method != http.MethodGet && r.Method != http.MethodHead
+}
+
+// Response is Toggl's response to a request.
+type Response struct {
+	StatusCode int
+	Status     string
+	Header     http.Header
+	Body       []byte
+}
+
+// RoundTripFunc sends a request and returns its response. The response may
+// be non-nil along with an error when Toggl returns an error status.
+type RoundTripFunc func(req *Request) (*Response, error)
+
+// Middleware wraps the sending of requests. It can inspect or change requests
+// before calling next, inspect or replace the response after, or not call next
+// at all:
+//
+//	func audit(next toggl.RoundTripFunc) toggl.RoundTripFunc {
+//		return func(req *toggl.Request) (*toggl.Response, error) {
+//			resp, err := next(req)
+//			if req.IsMutation() {
+//				log.Printf("%s %s: %v", req.Method, req.Path, err)
+//			}
+//			return resp, err
+//		}
+//	}
+type Middleware func(next RoundTripFunc) RoundTripFunc
+
+// Retry returns middleware that resends requests Toggl is rate limiting, and
+// requests other than POSTs that fail with a server error, up to maxRetries
+// times. It waits delay before the first retry and twice as long before each
+// following one, or longer if Toggl asks it to. Middleware added before Retry
+// sees a request once, with its final Retries count; middleware added after it
+// sees every attempt.
+func Retry(maxRetries int, delay time.Duration) Middleware {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *Request) (*Response, error) {
+			wait := delay
+			for {
+				resp, err := next(req)
+
+				var rerr *ResponseError
+				if req.Retries >= maxRetries || !errors.As(err, &rerr) || !rerr.Temporary() {
+					return resp, err
+				}
+				// a POST may have created something before the error
+				if rerr.StatusCode != http.StatusTooManyRequests && req.Method == http.MethodPost {
+					return resp, err
+				}
+
+				if after := rerr.RetryAfter(); after > wait {
+					wait = after
+				}
+				dlog.Printf("Retrying %s %s in %v: %v", req.Method, req.Path, wait, err)
+
+				timer := time.NewTimer(wait)
+				select {
+				case <-req.Context.Done():
+					timer.Stop()
+					return resp, err
+				case <-timer.C:
+				}
+				wait *= 2
+				req.Retries++
+			}
+		}
+	}
+}
+
+// Use adds middleware to the session. Middleware added first sees requests
+// first and responses last.
+func (session *Session) Use(middleware ...Middleware) {
+	session.Middleware = append(session.Middleware, middleware...)
+}
+
+// WithContext returns a copy of the session whose requests use ctx. The
+// context is passed to middleware and cancels requests when it's done.
+// Middleware added to either session later isn't shared with the other.
+func (session *Session) WithContext(ctx context.Context) *Session {
+	s := *session
+	s.ctx = ctx
+	s.Middleware = append([]Middleware(nil), session.Middleware...)
+	return &s
+}
+
+// Context returns the session's context, or context.Background if it has
+// none.
+func (session *Session) Context() context.Context {
+	if session.ctx != nil {
+		return session.ctx
+	}
+	return context.Background()
+}
+
+// operationName returns the name of the innermost exported Session method on
+// the call stack, such as "toggl.StartTimeEntry".
+func operationName() string {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	for {
+		frame, more := frames.Next()
+		if i := strings.Index(frame.Function, ".(*Session)."); i != -1 {
+			name := frame.Function[i+len(".(*Session)."):]
+			if name != "" && name[0] >= 'A' && name[0] <= 'Z' && !strings.Contains(name, ".") {
+				return "toggl." + name
+			}
+		}
+		if !more {
+			return ""
+		}
+	}
+}
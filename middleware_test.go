@@ -0,0 +1,77 @@
+package toggl
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		statuses []int
+		attempts int
+		err      bool
+	}{
+		{"success", "GET", []int{200}, 1, false},
+		{"rate limited", "GET", []int{429, 429, 200}, 3, false},
+		{"server error", "PUT", []int{503, 200}, 2, false},
+		{"gives up", "GET", []int{500, 500, 500, 500, 500}, 4, true},
+		{"not found", "GET", []int{404, 200}, 1, true},
+		{"rate limited post", "POST", []int{429, 200}, 2, false},
+		{"failed post", "POST", []int{502, 200}, 1, true},
+	}
+
+	for _, test := range tests {
+		attempts := 0
+		next := func(req *Request) (*Response, error) {
+			if req.Retries != attempts {
+				t.Errorf("%s: attempt %d has retry count %d", test.name, attempts, req.Retries)
+			}
+			status := test.statuses[attempts]
+			attempts++
+			resp := &Response{StatusCode: status, Header: http.Header{}}
+			if status >= 400 {
+				return resp, &ResponseError{StatusCode: status, Header: resp.Header}
+			}
+			return resp, nil
+		}
+
+		req := &Request{Context: context.Background(), Method: test.method}
+		_, err := Retry(3, time.Millisecond)(next)(req)
+		if (err != nil) != test.err {
+			t.Errorf("%s: got error %v", test.name, err)
+		}
+		if attempts != test.attempts || req.Retries != test.attempts-1 {
+			t.Errorf("%s: made %d attempts with %d retries, want %d", test.name, attempts, req.Retries, test.attempts)
+		}
+	}
+}
+
+func TestRetryCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	attempts := 0
+	next := func(req *Request) (*Response, error) {
+		attempts++
+		cancel()
+		header := http.Header{"Retry-After": []string{"60"}}
+		return &Response{StatusCode: 429, Header: header}, &ResponseError{StatusCode: 429, Header: header}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		Retry(3, time.Millisecond)(next)(&Request{Context: ctx, Method: "GET"})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("retrying didn't stop when the context was canceled")
+	}
+	if attempts != 1 {
+		t.Errorf("made %d attempts", attempts)
+	}
+}
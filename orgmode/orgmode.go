@@ -0,0 +1,312 @@
+/*
+Package orgmode converts between Toggl time entries and Emacs org-mode CLOCK
+entries.
+
+Time entries are rendered as a tree of headlines, with a level for the client,
+the project, and the entry description. Each description headline carries the
+entries' tags and a LOGBOOK drawer with one CLOCK line per entry:
+
+	#+TITLE: Time entries
+	* Acme
+	** Website
+	*** Code review                                              :review:
+	    :LOGBOOK:
+	    CLOCK: [2024-01-02 Tue 09:00]--[2024-01-02 Tue 10:30] =>  1:30
+	    :END:
+
+When reading, every closed CLOCK line is returned along with the path of
+headlines above it, which a PathMapping turns into project and client names.
+*/
+package orgmode
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jason0x43/go-toggl"
+	"github.com/jason0x43/go-toggl/importer"
+)
+
+// Placeholder headlines for entries without a client or project
+const (
+	NoClient  = "No client"
+	NoProject = "No project"
+)
+
+const timestampLayout = "2006-01-02 Mon 15:04"
+
+// Clock is a clocked time interval under a headline.
+type Clock struct {
+	// Path contains the titles of the headlines containing the clock, from
+	// the top level down.
+	Path  []string
+	Tags  []string
+	Start time.Time
+	End   time.Time
+	Line  int
+}
+
+// Write renders stopped time entries as org-mode headlines, using index to
+// resolve client and project names. Times are shown in loc.
+func Write(w io.Writer, entries []toggl.TimeEntry, index toggl.Index, loc *time.Location) error {
+	type group struct {
+		path    [3]string
+		tags    []string
+		entries []toggl.TimeEntry
+	}
+
+	groups := map[string]*group{}
+	var keys []string
+	for _, e := range entries {
+		if e.IsRunning() || e.Start == nil {
+			continue
+		}
+
+		client := index.ClientName(e.Pid)
+		if client == "" {
+			client = NoClient
+		}
+		project := index.ProjectName(e.Pid)
+		if project == "" {
+			project = NoProject
+		}
+		tags := orgTags(e.Tags)
+
+		key := strings.Join([]string{client, project, e.Description, strings.Join(tags, ":")}, "\x00")
+		g, ok := groups[key]
+		if !ok {
+			g = &group{path: [3]string{client, project, e.Description}, tags: tags}
+			groups[key] = g
+			keys = append(keys, key)
+		}
+		g.entries = append(g.entries, e)
+	}
+	sort.Strings(keys)
+
+	bw := bufio.NewWriter(w)
+	var last [3]string
+	for _, key := range keys {
+		g := groups[key]
+		for level := 0; level < 2; level++ {
+			if g.path[level] != last[level] || (level == 1 && g.path[0] != last[0]) {
+				fmt.Fprintf(bw, "%s %s\n", strings.Repeat("*", level+1), g.path[level])
+			}
+		}
+		last = g.path
+
+		title := g.path[2]
+		if title == "" {
+			title = "(no description)"
+		}
+		headline := "*** " + title
+		if len(g.tags) > 0 {
+			headline = fmt.Sprintf("%-70s :%s:", headline, strings.Join(g.tags, ":"))
+		}
+		fmt.Fprintln(bw, headline)
+
+		sort.Slice(g.entries, func(i, j int) bool {
+			return g.entries[i].Start.After(*g.entries[j].Start)
+		})
+		fmt.Fprintln(bw, "    :LOGBOOK:")
+		for _, e := range g.entries {
+			start := e.Start.In(loc)
+			end := start.Add(time.Duration(e.Duration) * time.Second)
+			minutes := e.Duration / 60
+			fmt.Fprintf(
+				bw,
+				"    CLOCK: [%s]--[%s] => %2d:%02d\n",
+				start.Format(timestampLayout),
+				end.Format(timestampLayout),
+				minutes/60,
+				minutes%60,
+			)
+		}
+		fmt.Fprintln(bw, "    :END:")
+	}
+
+	return bw.Flush()
+}
+
+var (
+	headlinePattern = regexp.MustCompile(`^(\*+)\s+(.*?)\s*$`)
+	tagsPattern     = regexp.MustCompile(`\s+(:[\w@#%:]+:)$`)
+	clockPattern    = regexp.MustCompile(`^\s*CLOCK:\s*\[([^\]]+)\]--\[([^\]]+)\]`)
+	keywordPattern  = regexp.MustCompile(`^(TODO|DONE)\s+`)
+)
+
+// Read reads the closed CLOCK lines in an org document. Timestamps are
+// interpreted in loc. Clocks appearing before the first headline are ignored.
+func Read(r io.Reader, loc *time.Location) ([]Clock, error) {
+	var clocks []Clock
+	var path []string
+	var tags [][]string
+
+	scanner := bufio.NewScanner(r)
+	n := 0
+	for scanner.Scan() {
+		n++
+		line := scanner.Text()
+
+		if m := headlinePattern.FindStringSubmatch(line); m != nil {
+			level := len(m[1])
+			title := m[2]
+
+			var headlineTags []string
+			if t := tagsPattern.FindStringSubmatch(title); t != nil {
+				headlineTags = strings.Split(strings.Trim(t[1], ":"), ":")
+				title = strings.TrimSpace(strings.TrimSuffix(title, t[0]))
+			}
+			title = keywordPattern.ReplaceAllString(title, "")
+
+			// skipped levels are recorded as empty titles
+			for len(path) < level-1 {
+				path = append(path, "")
+				tags = append(tags, nil)
+			}
+			path = append(path[:level-1], title)
+			tags = append(tags[:level-1], headlineTags)
+			continue
+		}
+
+		m := clockPattern.FindStringSubmatch(line)
+		if m == nil || len(path) == 0 {
+			continue
+		}
+
+		start, err := parseTimestamp(m[1], loc)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", n, err)
+		}
+		end, err := parseTimestamp(m[2], loc)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", n, err)
+		}
+
+		// tags are inherited from parent headlines
+		var clockTags []string
+		for _, t := range tags {
+			clockTags = append(clockTags, t...)
+		}
+
+		clocks = append(clocks, Clock{
+			Path:  append([]string{}, path...),
+			Tags:  clockTags,
+			Start: start,
+			End:   end,
+			Line:  n,
+		})
+	}
+
+	return clocks, scanner.Err()
+}
+
+// PathRule assigns a project and client to clocks under a headline path,
+// written with headline titles separated by slashes, such as "Acme/Website".
+type PathRule struct {
+	Path    string `json:"path"`
+	Project string `json:"project"`
+	Client  string `json:"client"`
+}
+
+// PathMapping is a list of path rules. The rule with the longest path that
+// prefixes a clock's path is used.
+type PathMapping []PathRule
+
+// Records converts clocks into import records. Clocks under a path matched by
+// the mapping get that rule's project and client and use the headline directly
+// containing them as their description. Other clocks are assumed to be laid
+// out as Write renders them, with client, project and description headlines.
+func Records(clocks []Clock, mapping PathMapping) []importer.Record {
+	var records []importer.Record
+	for _, c := range clocks {
+		if !c.End.After(c.Start) {
+			continue
+		}
+
+		record := importer.Record{
+			Line:        c.Line,
+			Description: c.Path[len(c.Path)-1],
+			Start:       c.Start,
+			End:         c.End,
+			Tags:        c.Tags,
+		}
+
+		if rule, ok := mapping.match(c.Path); ok {
+			record.Project = rule.Project
+			record.Client = rule.Client
+		} else {
+			switch len(c.Path) {
+			case 3:
+				record.Client = c.Path[0]
+				record.Project = c.Path[1]
+			case 2:
+				record.Project = c.Path[0]
+			}
+			if record.Client == NoClient {
+				record.Client = ""
+			}
+			if record.Project == NoProject {
+				record.Project = ""
+			}
+		}
+		if record.Description == "(no description)" {
+			record.Description = ""
+		}
+
+		records = append(records, record)
+	}
+	return records
+}
+
+func (m PathMapping) match(path []string) (PathRule, bool) {
+	var best PathRule
+	bestLen := -1
+	for _, rule := range m {
+		parts := strings.Split(strings.Trim(rule.Path, "/"), "/")
+		if len(parts) > len(path) || len(parts) <= bestLen {
+			continue
+		}
+		matched := true
+		for i, p := range parts {
+			if !strings.EqualFold(strings.TrimSpace(p), path[i]) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			best = rule
+			bestLen = len(parts)
+		}
+	}
+	return best, bestLen >= 0
+}
+
+func parseTimestamp(s string, loc *time.Location) (time.Time, error) {
+	// timestamps are "2024-01-02 Tue 09:00"; the day name is optional
+	fields := strings.Fields(s)
+	if len(fields) == 3 {
+		fields = []string{fields[0], fields[2]}
+	}
+	if len(fields) != 2 {
+		return time.Time{}, fmt.Errorf("invalid timestamp %q", s)
+	}
+	return time.ParseInLocation("2006-01-02 15:04", fields[0]+" "+fields[1], loc)
+}
+
+var invalidTagChars = regexp.MustCompile(`[^\w@#%]+`)
+
+// orgTags converts Toggl tags into valid org tags.
+func orgTags(tags []string) []string {
+	var result []string
+	for _, tag := range tags {
+		if t := strings.Trim(invalidTagChars.ReplaceAllString(tag, "_"), "_"); t != "" {
+			result = append(result, t)
+		}
+	}
+	return result
+}
@@ -0,0 +1,400 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jason0x43/go-toggl"
+)
+
+/*
+Parse parses a query written as a string, such as
+
+	project:acme/web tag:meeting -tag:internal billable:yes since:monday duration>2h "deploy"
+
+Terms are separated by spaces, and all of them must match. The terms are:
+
+	WORD, "SOME WORDS"   description contains the text, ignoring case
+	project:NAME         project with a name, or CLIENT/NAME, an ID, or "none"
+	client:NAME          project belongs to a client with a name or ID
+	workspace:NAME       entry is in a workspace with a name or ID
+	tag:NAME             entry has a tag
+	-tag:NAME            entry doesn't have a tag
+	billable:yes|no      entry is billable or not
+	running:yes|no       entry is running or not
+	since:DATE           entry starts on or after a day
+	from:DATE            the same as since:DATE
+	until:DATE           entry starts on or before a day
+	on:DATE              entry starts on a day
+	duration>DURATION    entry lasts longer than a duration; >=, < and <= also work
+
+Names are matched ignoring case, and a name may match several projects or
+clients. Values containing spaces can be quoted, as in tag:"client work". A
+DATE is YYYY-MM-DD, today, yesterday, or a day of the week, meaning the latest
+such day up to today. Unlike Query.Since, since:DATE is about when entries
+start, not when they were changed. A DURATION is written as 2h, 90m or 1h30m.
+Words containing a colon have to be quoted to be searched for.
+
+Names are looked up in the index, and dates are interpreted in the local time
+zone. Use a Parser for other settings. Without an index, project and client
+names are matched against the names Toggl returns with each entry.
+*/
+func Parse(s string, index toggl.Index) (Query, error) {
+	return Parser{Index: index}.Parse(s)
+}
+
+// Parser parses string queries.
+type Parser struct {
+	// Index is used to find projects, clients and workspaces by name.
+	Index toggl.Index
+
+	// Location is the time zone dates are interpreted in. It defaults to the
+	// local time zone.
+	Location *time.Location
+
+	// Now is the time relative dates such as "yesterday" are interpreted
+	// from. It defaults to the current time.
+	Now time.Time
+}
+
+// SyntaxError describes a problem with a string query. Pos is the byte offset
+// in Query where the problem was found.
+type SyntaxError struct {
+	Query string
+	Pos   int
+	Msg   string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s at position %d", e.Msg, e.Pos+1)
+}
+
+// Context returns the query with a marker under the position of the error,
+// for display in a fixed-width font.
+func (e *SyntaxError) Context() string {
+	return e.Query + "\n" + strings.Repeat(" ", e.Pos) + "^"
+}
+
+// Parse parses a string query. See the Parse function for the syntax.
+func (p Parser) Parse(s string) (Query, error) {
+	return p.Apply(New().WithIndex(p.Index), s)
+}
+
+// Apply adds the conditions in a string query to q. Dates in the string
+// replace the corresponding ends of q's time range.
+func (p Parser) Apply(q Query, s string) (Query, error) {
+	terms, err := scan(s)
+	if err != nil {
+		return q, err
+	}
+
+	fail := func(pos int, format string, args ...interface{}) (Query, error) {
+		return q, &SyntaxError{Query: s, Pos: pos, Msg: fmt.Sprintf(format, args...)}
+	}
+
+	rangePos := -1
+	for _, t := range terms {
+		if t.field == "" {
+			q = q.Text(t.value)
+			continue
+		}
+
+		if t.negated && t.field != "tag" {
+			return fail(t.pos, "%s can't be negated", t.field)
+		}
+		if t.op != ":" && t.field != "duration" {
+			return fail(t.valuePos-len(t.op), "expected ':' after %s", t.field)
+		}
+		if t.value == "" {
+			return fail(t.valuePos, "missing value for %s", t.field)
+		}
+
+		switch t.field {
+		case "project":
+			if strings.EqualFold(t.value, "none") {
+				q = q.NoProject()
+				break
+			}
+			if p.Index.Projects == nil {
+				q = q.ProjectNamed(t.value)
+				break
+			}
+			ids := p.projects(t.value)
+			if len(ids) == 0 {
+				return fail(t.valuePos, "unknown project %q", t.value)
+			}
+			q = q.Project(ids...)
+
+		case "client":
+			if p.Index.Clients == nil {
+				q = q.ClientNamed(t.value)
+				break
+			}
+			ids := p.clients(t.value)
+			if len(ids) == 0 {
+				return fail(t.valuePos, "unknown client %q", t.value)
+			}
+			q = q.Client(ids...)
+
+		case "workspace":
+			wid := p.workspace(t.value)
+			if wid == 0 {
+				return fail(t.valuePos, "unknown workspace %q", t.value)
+			}
+			q = q.Workspace(wid)
+
+		case "tag":
+			if t.negated {
+				q = q.NotTag(t.value)
+			} else {
+				q = q.Tag(t.value)
+			}
+
+		case "billable", "running":
+			var yes bool
+			switch strings.ToLower(t.value) {
+			case "yes", "true":
+				yes = true
+			case "no", "false":
+			default:
+				return fail(t.valuePos, "%s must be yes or no", t.field)
+			}
+			if t.field == "billable" {
+				q = q.Billable(yes)
+			} else if yes {
+				q = q.Running()
+			} else {
+				q = q.Stopped()
+			}
+
+		case "since", "from", "until", "on":
+			day, ok := p.date(t.value)
+			if !ok {
+				return fail(t.valuePos, "invalid date %q", t.value)
+			}
+			if t.field != "until" {
+				q.start = day
+			}
+			if t.field != "since" && t.field != "from" {
+				q.end = day.AddDate(0, 0, 1)
+			}
+			rangePos = t.pos
+
+		case "duration":
+			d, err := time.ParseDuration(t.value)
+			if err != nil || d < 0 {
+				return fail(t.valuePos, "invalid duration %q", t.value)
+			}
+			switch t.op {
+			case ">":
+				q = q.MinDuration(d + time.Nanosecond)
+			case ">=":
+				q = q.MinDuration(d)
+			case "<":
+				q = q.MaxDuration(d - time.Nanosecond)
+			case "<=":
+				q = q.MaxDuration(d)
+			default:
+				return fail(t.valuePos-len(t.op), "expected >, >=, < or <= after duration")
+			}
+
+		default:
+			return fail(t.pos, "unknown field %q", t.field)
+		}
+	}
+
+	if rangePos >= 0 && !q.start.IsZero() && !q.end.IsZero() && !q.start.Before(q.end) {
+		return fail(rangePos, "start date must be before end date")
+	}
+	return q, nil
+}
+
+// projects returns the IDs of the projects matching a name, a CLIENT/NAME
+// pair or an ID.
+func (p Parser) projects(name string) []int {
+	if id, err := strconv.Atoi(name); err == nil {
+		if _, ok := p.Index.Projects[id]; ok {
+			return []int{id}
+		}
+	}
+
+	var ids []int
+	for _, project := range p.Index.Projects {
+		if strings.EqualFold(project.Name, name) {
+			ids = append(ids, project.ID)
+		}
+	}
+	if len(ids) > 0 {
+		return ids
+	}
+
+	// project names may contain slashes, so a client is only looked for if no
+	// project has the full name
+	i := strings.Index(name, "/")
+	if i < 0 {
+		return nil
+	}
+	clients := p.clients(name[:i])
+	for _, project := range p.Index.Projects {
+		if project.Cid != nil && containsInt(clients, *project.Cid) &&
+			strings.EqualFold(project.Name, name[i+1:]) {
+			ids = append(ids, project.ID)
+		}
+	}
+	return ids
+}
+
+// clients returns the IDs of the clients matching a name or ID.
+func (p Parser) clients(name string) []int {
+	if id, err := strconv.Atoi(name); err == nil {
+		if _, ok := p.Index.Clients[id]; ok {
+			return []int{id}
+		}
+	}
+
+	var ids []int
+	for _, client := range p.Index.Clients {
+		if strings.EqualFold(client.Name, name) {
+			ids = append(ids, client.ID)
+		}
+	}
+	return ids
+}
+
+// workspace returns the ID of the workspace matching a name or ID, or 0.
+func (p Parser) workspace(name string) int {
+	if id, err := strconv.Atoi(name); err == nil {
+		if _, ok := p.Index.Workspaces[id]; ok {
+			return id
+		}
+	}
+	for _, w := range p.Index.Workspaces {
+		if strings.EqualFold(w.Name, name) {
+			return w.ID
+		}
+	}
+	return 0
+}
+
+// date returns the start of the day a date names.
+func (p Parser) date(s string) (time.Time, bool) {
+	loc := p.Location
+	if loc == nil {
+		loc = time.Local
+	}
+	now := p.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	now = now.In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+
+	switch strings.ToLower(s) {
+	case "today":
+		return today, true
+	case "yesterday":
+		return today.AddDate(0, 0, -1), true
+	}
+	for wd := time.Sunday; wd <= time.Saturday; wd++ {
+		if strings.EqualFold(s, wd.String()) {
+			days := (int(today.Weekday()) - int(wd) + 7) % 7
+			return today.AddDate(0, 0, -days), true
+		}
+	}
+
+	day, err := time.ParseInLocation("2006-01-02", s, loc)
+	return day, err == nil
+}
+
+// term is one space separated part of a string query. Text terms have no
+// field.
+type term struct {
+	pos      int
+	negated  bool
+	field    string
+	op       string
+	value    string
+	valuePos int
+}
+
+// scan splits a string query into terms.
+func scan(s string) ([]term, error) {
+	var terms []term
+	i := 0
+	for {
+		for i < len(s) && isSpace(s[i]) {
+			i++
+		}
+		if i == len(s) {
+			return terms, nil
+		}
+
+		t := term{pos: i}
+		j := i
+		if s[j] == '-' {
+			j++
+		}
+		k := j
+		for k < len(s) && s[k] >= 'a' && s[k] <= 'z' {
+			k++
+		}
+		if k > j {
+			for _, op := range []string{":", ">=", "<=", ">", "<"} {
+				if strings.HasPrefix(s[k:], op) {
+					t.negated = j > i
+					t.field = s[j:k]
+					t.op = op
+					i = k + len(op)
+					break
+				}
+			}
+		}
+
+		t.valuePos = i
+		value, end, err := scanValue(s, i)
+		if err != nil {
+			return nil, err
+		}
+		t.value = value
+		i = end
+		terms = append(terms, t)
+	}
+}
+
+// scanValue reads a value starting at i, which runs until the next space
+// outside of quotes. Quotes are removed, and a backslash in quotes escapes
+// the next character.
+func scanValue(s string, i int) (string, int, error) {
+	var b strings.Builder
+	for i < len(s) && !isSpace(s[i]) {
+		if s[i] != '"' {
+			b.WriteByte(s[i])
+			i++
+			continue
+		}
+
+		start := i
+		i++
+		for {
+			if i == len(s) {
+				return "", i, &SyntaxError{Query: s, Pos: start, Msg: "unterminated quote"}
+			}
+			if s[i] == '"' {
+				i++
+				break
+			}
+			if s[i] == '\\' && i+1 < len(s) {
+				i++
+			}
+			b.WriteByte(s[i])
+			i++
+		}
+	}
+	return b.String(), i, nil
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
@@ -0,0 +1,154 @@
+package query
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jason0x43/go-toggl"
+)
+
+func testParser() Parser {
+	acme := 1
+	index := toggl.NewIndex(toggl.Account{
+		Workspaces: []toggl.Workspace{{ID: 1, Name: "Work"}},
+		Clients:    []toggl.Client{{ID: acme, Wid: 1, Name: "Acme"}},
+		Projects: []toggl.Project{
+			{ID: 10, Wid: 1, Cid: &acme, Name: "Web"},
+			{ID: 20, Wid: 1, Name: "Ops"},
+		},
+	})
+	// a Friday
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	return Parser{Index: index, Location: time.UTC, Now: now}
+}
+
+func TestParseRange(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2026, 10, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		query      string
+		start, end time.Time
+	}{
+		{"since:monday", day(12), time.Time{}},
+		{"from:monday", day(12), time.Time{}},
+		{"since:2026-10-01 until:yesterday", day(1), day(16)},
+		{"until:today", time.Time{}, day(17)},
+		{"on:friday", day(16), day(17)},
+		{"since:Thursday", day(15), time.Time{}},
+	}
+
+	for _, test := range tests {
+		q, err := testParser().Parse(test.query)
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %v", test.query, err)
+			continue
+		}
+		if !q.start.Equal(test.start) || !q.end.Equal(test.end) {
+			t.Errorf("Parse(%q) has range %v - %v, want %v - %v", test.query, q.start, q.end, test.start, test.end)
+		}
+		if !q.since.IsZero() {
+			t.Errorf("Parse(%q) set Since to %v", test.query, q.since)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	start := time.Date(2026, 10, 12, 9, 0, 0, 0, time.UTC)
+	stop := start.Add(time.Hour)
+	web, ops := 10, 20
+	entry := toggl.TimeEntry{
+		Wid:         1,
+		Pid:         &web,
+		Description: "Deploy the site",
+		Start:       &start,
+		Stop:        &stop,
+		Duration:    3600,
+		Tags:        []string{"release"},
+		Billable:    true,
+	}
+
+	tests := []struct {
+		query string
+		match bool
+	}{
+		{"since:monday project:web", true},
+		{"since:tuesday", false},
+		{"client:acme tag:release billable:yes", true},
+		{"project:acme/web workspace:work", true},
+		{"project:ops", false},
+		{"-tag:release", false},
+		{`deploy "THE SITE"`, true},
+		{"duration>1h", false},
+		{"duration>=1h running:no", true},
+		{"project:none", false},
+	}
+
+	for _, test := range tests {
+		q, err := testParser().Parse(test.query)
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %v", test.query, err)
+			continue
+		}
+		if m := q.Match(entry); m != test.match {
+			t.Errorf("Parse(%q).Match() = %v, want %v", test.query, m, test.match)
+		}
+	}
+
+	// projects can also be given by ID
+	q, err := testParser().Parse("project:20")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry.Pid = &ops
+	if !q.Match(entry) {
+		t.Errorf("Parse(%q) didn't match project %d", "project:20", ops)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		query string
+		pos   int
+		msg   string
+	}{
+		{"tag:x bogus:1", 6, `unknown field "bogus"`},
+		{"tag:", 4, "missing value for tag"},
+		{"billable>yes", 8, "expected ':' after billable"},
+		{"duration:2h", 8, "expected >, >=, < or <= after duration"},
+		{"duration>soon", 9, `invalid duration "soon"`},
+		{"web -project:web", 4, "project can't be negated"},
+		{`tag:"client work`, 4, "unterminated quote"},
+		{"project:mobile", 8, `unknown project "mobile"`},
+		{"client:globex", 7, `unknown client "globex"`},
+		{"since:someday", 6, `invalid date "someday"`},
+		{"billable:maybe", 9, "billable must be yes or no"},
+		{"since:tuesday until:monday", 14, "start date must be before end date"},
+	}
+
+	for _, test := range tests {
+		_, err := testParser().Parse(test.query)
+		serr, ok := err.(*SyntaxError)
+		if !ok {
+			t.Errorf("Parse(%q) returned %v, want a syntax error", test.query, err)
+			continue
+		}
+		if serr.Pos != test.pos || serr.Msg != test.msg {
+			t.Errorf("Parse(%q) failed with %q at %d, want %q at %d", test.query, serr.Msg, serr.Pos, test.msg, test.pos)
+		}
+		if serr.Query != test.query {
+			t.Errorf("Parse(%q) error has query %q", test.query, serr.Query)
+		}
+	}
+}
+
+func TestSyntaxError(t *testing.T) {
+	err := &SyntaxError{Query: "tag:x bogus:1", Pos: 6, Msg: `unknown field "bogus"`}
+	if s := err.Error(); s != `unknown field "bogus" at position 7` {
+		t.Errorf("Error() = %q", s)
+	}
+	if s := err.Context(); s != "tag:x bogus:1\n      ^" {
+		t.Errorf("Context() = %q", s)
+	}
+}
@@ -0,0 +1,456 @@
+/*
+Package query selects time entries by project, client, tags, billable flag,
+description, time and duration.
+
+A query is built up from conditions, all of which must match:
+
+	q := query.New().
+		WithIndex(toggl.NewIndex(account)).
+		Between(monday, now).
+		Client(clientID).
+		Tag("meeting").
+		NotTag("internal").
+		Billable(true)
+
+	entries, err := q.Fetch(&session)
+
+Queries can also be written as strings and parsed with Parse:
+
+	q, err := query.Parse(`client:acme tag:meeting -tag:internal billable:yes since:monday`, index)
+
+Fetch and FetchDetailed pass the time range on to Toggl and apply the other
+conditions to the entries Toggl returns. Match and Filter apply a query to
+entries that have already been loaded.
+*/
+package query
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jason0x43/go-toggl"
+)
+
+// Query is a set of conditions on time entries.
+type Query struct {
+	wid          int
+	start        time.Time
+	end          time.Time
+	since        time.Time
+	projects     []int
+	projectNames []string
+	noProject    bool
+	clients      []int
+	clientNames  []string
+	tags         []string
+	notTags      []string
+	billable     *bool
+	description  *regexp.Regexp
+	texts        []string
+	running      *bool
+	minDuration  time.Duration
+	maxDuration  time.Duration
+	index        toggl.Index
+	now          time.Time
+}
+
+// New returns a query that matches every entry.
+func New() Query {
+	return Query{}
+}
+
+// Workspace matches entries in a workspace.
+func (q Query) Workspace(wid int) Query {
+	q.wid = wid
+	return q
+}
+
+// Project matches entries in any of the given projects. Conditions on projects
+// and NoProject are combined, so an entry matches if it's in one of the
+// projects or has none.
+func (q Query) Project(ids ...int) Query {
+	q.projects = append(append([]int{}, q.projects...), ids...)
+	return q
+}
+
+// ProjectNamed matches entries in projects with any of the given names,
+// ignoring case. It's combined with Project and NoProject, and is meant for
+// queries without an index: entries from Fetch carry their project names, as
+// do report entries.
+func (q Query) ProjectNamed(names ...string) Query {
+	q.projectNames = append(append([]string{}, q.projectNames...), names...)
+	return q
+}
+
+// NoProject matches entries without a project.
+func (q Query) NoProject() Query {
+	q.noProject = true
+	return q
+}
+
+// Client matches entries in projects belonging to any of the given clients.
+// The query's index is used to find the client of an entry's project.
+func (q Query) Client(ids ...int) Query {
+	q.clients = append(append([]int{}, q.clients...), ids...)
+	return q
+}
+
+// ClientNamed matches entries in projects belonging to clients with any of the
+// given names, ignoring case. It's combined with Client, and like ProjectNamed
+// doesn't need an index.
+func (q Query) ClientNamed(names ...string) Query {
+	q.clientNames = append(append([]string{}, q.clientNames...), names...)
+	return q
+}
+
+// Tag matches entries that have a tag. Entries must have every tag given.
+func (q Query) Tag(tag string) Query {
+	q.tags = append(append([]string{}, q.tags...), tag)
+	return q
+}
+
+// NotTag matches entries that don't have a tag.
+func (q Query) NotTag(tag string) Query {
+	q.notTags = append(append([]string{}, q.notTags...), tag)
+	return q
+}
+
+// Billable matches billable or non-billable entries.
+func (q Query) Billable(billable bool) Query {
+	q.billable = &billable
+	return q
+}
+
+// DescriptionMatches matches entries with descriptions matching a regular
+// expression.
+func (q Query) DescriptionMatches(re *regexp.Regexp) Query {
+	q.description = re
+	return q
+}
+
+// Text matches entries whose descriptions contain a string, ignoring case.
+// Entries must contain every string given.
+func (q Query) Text(s string) Query {
+	q.texts = append(append([]string{}, q.texts...), strings.ToLower(s))
+	return q
+}
+
+// Between matches entries that start at or after start and before end. A zero
+// time leaves that end of the range open.
+func (q Query) Between(start, end time.Time) Query {
+	q.start, q.end = start, end
+	return q
+}
+
+// Since matches entries created, changed or deleted since a time. Deleted
+// entries are only returned by Fetch, and only when Since is set.
+func (q Query) Since(t time.Time) Query {
+	q.since = t
+	return q
+}
+
+// Running matches the running entry.
+func (q Query) Running() Query {
+	running := true
+	q.running = &running
+	return q
+}
+
+// Stopped matches entries that aren't running.
+func (q Query) Stopped() Query {
+	running := false
+	q.running = &running
+	return q
+}
+
+// MinDuration matches entries that last at least d. Running entries are
+// measured up to the current time.
+func (q Query) MinDuration(d time.Duration) Query {
+	q.minDuration = d
+	return q
+}
+
+// MaxDuration matches entries that last at most d.
+func (q Query) MaxDuration(d time.Duration) Query {
+	q.maxDuration = d
+	return q
+}
+
+// WithIndex sets the index used to find the clients of projects.
+func (q Query) WithIndex(index toggl.Index) Query {
+	q.index = index
+	return q
+}
+
+// At sets the time running entries are measured to. It defaults to the
+// current time.
+func (q Query) At(now time.Time) Query {
+	q.now = now
+	return q
+}
+
+// Match returns true if an entry matches the query.
+func (q Query) Match(e toggl.TimeEntry) bool {
+	if q.wid != 0 && e.Wid != q.wid {
+		return false
+	}
+	if !q.matchTime(e.Start) {
+		return false
+	}
+	if !q.since.IsZero() && e.At != nil && e.At.Before(q.since) {
+		return false
+	}
+	if !q.matchProject(e.Pid, e.ProjectName) {
+		return false
+	}
+	if len(q.clients) > 0 || len(q.clientNames) > 0 {
+		cid := 0
+		if e.Pid != nil {
+			if p, ok := q.index.Projects[*e.Pid]; ok && p.Cid != nil {
+				cid = *p.Cid
+			}
+		}
+		if !containsInt(q.clients, cid) && !containsName(q.clientNames, e.ClientName) {
+			return false
+		}
+	}
+	if !q.matchTags(e.Tags) {
+		return false
+	}
+	if q.billable != nil && e.Billable != *q.billable {
+		return false
+	}
+	if !q.matchDescription(e.Description) {
+		return false
+	}
+	if q.running != nil && e.IsRunning() != *q.running {
+		return false
+	}
+
+	d := time.Duration(e.Duration) * time.Second
+	if e.IsRunning() {
+		d = q.currentTime().Sub(e.StartTime())
+	}
+	return q.matchDuration(d)
+}
+
+// MatchDetailed returns true if an entry from a detailed report matches the
+// query. Reports identify clients by name, so the query's index is used to
+// find the names of the clients being matched. Report entries are never
+// running and have no workspace, so Running never matches them and Workspace
+// is ignored.
+func (q Query) MatchDetailed(e toggl.DetailedTimeEntry) bool {
+	if !q.matchTime(e.Start) {
+		return false
+	}
+	if !q.since.IsZero() && e.Updated != nil && e.Updated.Before(q.since) {
+		return false
+	}
+
+	var pid *int
+	if e.Pid != 0 {
+		pid = &e.Pid
+	}
+	if !q.matchProject(pid, e.Project) {
+		return false
+	}
+	if len(q.clients) > 0 || len(q.clientNames) > 0 {
+		found := containsName(q.clientNames, e.Client)
+		for _, cid := range q.clients {
+			if c, ok := q.index.Clients[cid]; ok && c.Name == e.Client {
+				found = true
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if !q.matchTags(e.Tags) {
+		return false
+	}
+	if q.billable != nil && e.Billable != *q.billable {
+		return false
+	}
+	if !q.matchDescription(e.Description) {
+		return false
+	}
+	if q.running != nil && *q.running {
+		return false
+	}
+
+	// report durations are in milliseconds
+	return q.matchDuration(time.Duration(e.Duration) * time.Millisecond)
+}
+
+// Filter returns the entries that match the query.
+func (q Query) Filter(entries []toggl.TimeEntry) []toggl.TimeEntry {
+	var matched []toggl.TimeEntry
+	for _, e := range entries {
+		if q.Match(e) {
+			matched = append(matched, e)
+		}
+	}
+	return matched
+}
+
+// FilterDetailed returns the report entries that match the query.
+func (q Query) FilterDetailed(entries []toggl.DetailedTimeEntry) []toggl.DetailedTimeEntry {
+	var matched []toggl.DetailedTimeEntry
+	for _, e := range entries {
+		if q.MatchDetailed(e) {
+			matched = append(matched, e)
+		}
+	}
+	return matched
+}
+
+// Range returns the time range entries are loaded for. Open ends default to a
+// week before the end and the end of the current day.
+func (q Query) Range() (start, end time.Time) {
+	now := q.currentTime()
+	start, end = q.start, q.end
+	if end.IsZero() {
+		y, m, d := now.Date()
+		end = time.Date(y, m, d+1, 0, 0, 0, 0, now.Location())
+	}
+	if start.IsZero() {
+		start = end.AddDate(0, 0, -7)
+	}
+	return
+}
+
+// Fetch loads the matching entries from Toggl. If Since is set, the entries
+// changed since then are loaded, including deleted ones; otherwise the entries
+// in the query's Range are loaded. The entries come with the names of their
+// projects and clients, which ProjectNamed and ClientNamed match against.
+func (q Query) Fetch(session *toggl.Session) ([]toggl.TimeEntry, error) {
+	var entries []toggl.TimeEntry
+	var err error
+	if !q.since.IsZero() {
+		entries, err = session.GetTimeEntriesSince(q.since)
+	} else {
+		entries, err = session.GetTimeEntries(q.Range())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return q.Filter(entries), nil
+}
+
+// FetchDetailed loads the matching entries in the query's Range from the
+// detailed reports of a workspace.
+func (q Query) FetchDetailed(session *toggl.Session, wid int) ([]toggl.DetailedTimeEntry, error) {
+	start, end := q.Range()
+	since := start.Format("2006-01-02")
+	until := end.Add(-time.Nanosecond).Format("2006-01-02")
+
+	var entries []toggl.DetailedTimeEntry
+	for page := 1; ; page++ {
+		report, err := session.GetDetailedReport(wid, since, until, page)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, report.Data...)
+		if len(report.Data) == 0 || len(entries) >= report.TotalCount {
+			break
+		}
+	}
+
+	return q.FilterDetailed(entries), nil
+}
+
+func (q Query) currentTime() time.Time {
+	if q.now.IsZero() {
+		return time.Now()
+	}
+	return q.now
+}
+
+func (q Query) matchTime(start *time.Time) bool {
+	if q.start.IsZero() && q.end.IsZero() {
+		return true
+	}
+	if start == nil {
+		return false
+	}
+	if !q.start.IsZero() && start.Before(q.start) {
+		return false
+	}
+	return q.end.IsZero() || start.Before(q.end)
+}
+
+func (q Query) matchProject(pid *int, name string) bool {
+	if len(q.projects) == 0 && len(q.projectNames) == 0 && !q.noProject {
+		return true
+	}
+	if pid == nil {
+		return q.noProject
+	}
+	return containsInt(q.projects, *pid) || containsName(q.projectNames, name)
+}
+
+func (q Query) matchTags(tags []string) bool {
+	for _, t := range q.tags {
+		if !containsString(tags, t) {
+			return false
+		}
+	}
+	for _, t := range q.notTags {
+		if containsString(tags, t) {
+			return false
+		}
+	}
+	return true
+}
+
+func (q Query) matchDescription(description string) bool {
+	if q.description != nil && !q.description.MatchString(description) {
+		return false
+	}
+	lower := strings.ToLower(description)
+	for _, t := range q.texts {
+		if !strings.Contains(lower, t) {
+			return false
+		}
+	}
+	return true
+}
+
+func (q Query) matchDuration(d time.Duration) bool {
+	if q.minDuration > 0 && d < q.minDuration {
+		return false
+	}
+	return q.maxDuration <= 0 || d <= q.maxDuration
+}
+
+func containsInt(list []int, v int) bool {
+	for _, x := range list {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+// containsName returns true if a non-empty name is in a list, ignoring case.
+func containsName(list []string, name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, x := range list {
+		if strings.EqualFold(x, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(list []string, v string) bool {
+	for _, x := range list {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
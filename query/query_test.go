@@ -0,0 +1,125 @@
+package query
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jason0x43/go-toggl"
+)
+
+func TestFetchMeta(t *testing.T) {
+	toggl.DisableLog()
+
+	var queries []string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v9/me/time_entries" {
+			http.NotFound(w, r)
+			return
+		}
+		queries = append(queries, r.URL.RawQuery)
+		w.Write([]byte(`[
+			{"id":1,"workspace_id":1,"project_id":10,"description":"Reviews","start":"2026-10-16T09:00:00Z","duration":3600,"project_name":"Web","client_name":"Acme"},
+			{"id":2,"workspace_id":1,"project_id":20,"description":"Deploy","start":"2026-10-16T10:00:00Z","duration":3600,"project_name":"Web","client_name":"Globex"},
+			{"id":3,"workspace_id":1,"project_id":30,"description":"Planning","start":"2026-10-16T11:00:00Z","duration":3600,"project_name":"Ops","client_name":"Acme"},
+			{"id":4,"workspace_id":1,"description":"Lunch","start":"2026-10-16T12:00:00Z","duration":3600}
+		]`))
+	}))
+	defer api.Close()
+
+	session := toggl.OpenSession("token")
+	session.Use(func(next toggl.RoundTripFunc) toggl.RoundTripFunc {
+		return func(req *toggl.Request) (*toggl.Response, error) {
+			req.API = strings.Replace(req.API, "https://api.track.toggl.com", api.URL, 1)
+			return next(req)
+		}
+	})
+
+	day := time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		query string
+		ids   []int
+	}{
+		{"", []int{1, 2, 3, 4}},
+		{"project:web", []int{1, 2}},
+		{"client:acme", []int{1, 3}},
+		{"project:web client:ACME", []int{1}},
+		{"project:none", []int{4}},
+		{"project:mobile", nil},
+	}
+
+	for _, test := range tests {
+		// without an index, names are matched against the names Toggl returns
+		q, err := Parser{Location: time.UTC, Now: day}.Apply(New().Between(day, day.AddDate(0, 0, 1)), test.query)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", test.query, err)
+		}
+		entries, err := q.Fetch(&session)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []int
+		for _, e := range entries {
+			ids = append(ids, e.ID)
+		}
+		if !equalInts(ids, test.ids) {
+			t.Errorf("Fetch(%q) = %v, want %v", test.query, ids, test.ids)
+		}
+	}
+
+	for _, query := range queries {
+		if !strings.Contains(query, "meta=true") {
+			t.Errorf("entries were loaded without metadata: %s", query)
+		}
+	}
+
+	// loading changed entries also asks for metadata
+	queries = nil
+	if _, err := New().Since(day).Fetch(&session); err != nil {
+		t.Fatal(err)
+	}
+	if len(queries) != 1 || !strings.Contains(queries[0], "meta=true") || !strings.Contains(queries[0], "since=") {
+		t.Errorf("changed entries were loaded with %v", queries)
+	}
+}
+
+func TestMatchDetailedNames(t *testing.T) {
+	entries := []toggl.DetailedTimeEntry{
+		{ID: 1, Pid: 10, Project: "Web", Client: "Acme"},
+		{ID: 2, Pid: 20, Project: "Web", Client: "Globex"},
+		{ID: 3, Pid: 30, Project: "Ops", Client: "Acme"},
+	}
+
+	tests := []struct {
+		q   Query
+		ids []int
+	}{
+		{New().ProjectNamed("web"), []int{1, 2}},
+		{New().ClientNamed("acme"), []int{1, 3}},
+		{New().ProjectNamed("ops").Project(20), []int{2, 3}},
+	}
+
+	for i, test := range tests {
+		var ids []int
+		for _, e := range test.q.FilterDetailed(entries) {
+			ids = append(ids, e.ID)
+		}
+		if !equalInts(ids, test.ids) {
+			t.Errorf("query %d matched %v, want %v", i, ids, test.ids)
+		}
+	}
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,94 @@
+package repair
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/jason0x43/go-toggl"
+)
+
+// Apply sends edits to Toggl in order. Each successful edit is written to
+// undo as a line of JSON, with the After entry of creates and updates
+// replaced by the entry Toggl returned. Apply stops at the first error,
+// returning the edits that were applied.
+func Apply(session *toggl.Session, edits []Edit, undo io.Writer) ([]Edit, error) {
+	var applied []Edit
+	enc := json.NewEncoder(undo)
+
+	for _, edit := range edits {
+		var err error
+		switch edit.Action {
+		case Create:
+			var created toggl.TimeEntry
+			if created, err = session.CreateTimeEntry(*edit.After); err == nil {
+				edit.After = &created
+			}
+		case Update:
+			var updated toggl.TimeEntry
+			if updated, err = session.UpdateTimeEntry(*edit.After); err == nil {
+				edit.After = &updated
+			}
+		case Delete:
+			_, err = session.DeleteTimeEntry(*edit.Before)
+		}
+		if err != nil {
+			return applied, fmt.Errorf("Error applying %s: %v", edit.Action, err)
+		}
+
+		applied = append(applied, edit)
+		if err := enc.Encode(edit); err != nil {
+			return applied, fmt.Errorf("Error writing undo log: %v", err)
+		}
+	}
+
+	return applied, nil
+}
+
+// ReadUndoLog reads the edits recorded by Apply.
+func ReadUndoLog(r io.Reader) ([]Edit, error) {
+	var edits []Edit
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var edit Edit
+		if err := json.Unmarshal(scanner.Bytes(), &edit); err != nil {
+			return nil, fmt.Errorf("Error reading undo log: %v", err)
+		}
+		edits = append(edits, edit)
+	}
+	return edits, scanner.Err()
+}
+
+// Undo reverts applied edits in reverse order: created entries are deleted,
+// updated entries are restored, and deleted entries are recreated. Recreated
+// entries get new IDs.
+func Undo(session *toggl.Session, applied []Edit) error {
+	for i := len(applied) - 1; i >= 0; i-- {
+		edit := applied[i]
+		var err error
+		switch edit.Action {
+		case Create:
+			_, err = session.DeleteTimeEntry(*edit.After)
+		case Update:
+			_, err = session.UpdateTimeEntry(*edit.Before)
+		case Delete:
+			_, err = session.CreateTimeEntry(*edit.Before)
+		}
+		if err != nil {
+			return fmt.Errorf("Error undoing %s of entry %d: %v", edit.Action, entryID(edit), err)
+		}
+	}
+	return nil
+}
+
+func entryID(edit Edit) int {
+	if edit.Before != nil {
+		return edit.Before.ID
+	}
+	return edit.After.ID
+}
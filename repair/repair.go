@@ -0,0 +1,361 @@
+/*
+Package repair fixes common problems in time entries.
+
+A Plan starts from a list of time entries and applies repair operations to an
+in-memory copy of them. Operations can be combined; each one sees the results
+of the previous ones. The plan's Edits are the creates, updates and deletes
+needed to turn the original entries into the repaired ones, which can be
+previewed with WriteDiff and sent to Toggl with Apply.
+
+Apply records every change it makes in an undo log, which Undo can later use
+to restore the original entries.
+*/
+package repair
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jason0x43/go-toggl"
+	"github.com/jason0x43/go-toggl/lint"
+)
+
+// Action is a kind of edit.
+type Action int
+
+// Edit actions
+const (
+	Create Action = iota
+	Update
+	Delete
+)
+
+var actionNames = map[Action]string{
+	Create: "create",
+	Update: "update",
+	Delete: "delete",
+}
+
+func (a Action) String() string {
+	return actionNames[a]
+}
+
+// Edit is a change to a single time entry.
+type Edit struct {
+	Action Action
+
+	// Before is the original entry, or nil for creates.
+	Before *toggl.TimeEntry
+
+	// After is the repaired entry, or nil for deletes. The IDs of entries to
+	// be created are negative.
+	After *toggl.TimeEntry
+
+	// Reasons explain why the entry was changed.
+	Reasons []string
+}
+
+// Placeholder describes the entries used to fill gaps.
+type Placeholder struct {
+	Wid         int
+	Pid         *int
+	Description string
+	Tags        []string
+}
+
+// Plan is a set of repairs to a list of time entries.
+type Plan struct {
+	originals map[int]toggl.TimeEntry
+	entries   []toggl.TimeEntry
+	reasons   map[int][]string
+	nextID    int
+}
+
+// NewPlan starts a plan for repairing a list of entries.
+func NewPlan(entries []toggl.TimeEntry) *Plan {
+	p := &Plan{
+		originals: map[int]toggl.TimeEntry{},
+		reasons:   map[int][]string{},
+		nextID:    -1,
+	}
+	for _, e := range entries {
+		if e.Start == nil {
+			continue
+		}
+		p.originals[e.ID] = e.Copy()
+		p.entries = append(p.entries, e.Copy())
+	}
+	p.sort()
+	return p
+}
+
+// Entries returns the repaired entries, ordered by start time.
+func (p *Plan) Entries() []toggl.TimeEntry {
+	return append([]toggl.TimeEntry{}, p.entries...)
+}
+
+// TrimOverlaps shortens entries that overlap the entries after them so they
+// stop when the next entry starts. If the later entry lies entirely within the
+// earlier one, the remainder of the earlier entry is moved after it. Running
+// entries are never changed.
+func (p *Plan) TrimOverlaps() {
+	// remainders may overlap later entries themselves
+	for p.trimOverlaps() {
+	}
+}
+
+func (p *Plan) trimOverlaps() (changed bool) {
+	var remainders []toggl.TimeEntry
+	for i := range p.entries {
+		a := &p.entries[i]
+		if a.IsRunning() {
+			continue
+		}
+		for j := i + 1; j < len(p.entries); j++ {
+			b := p.entries[j]
+			aStop := endTime(*a)
+			if !b.Start.Before(aStop) {
+				break
+			}
+
+			bStop := endTime(b)
+			if !b.IsRunning() && bStop.Before(aStop) {
+				// a may only have a duration, and SetStartTime needs a stop
+				// time to keep
+				rest := a.Copy()
+				rest.ID = p.newID()
+				rest.Stop = &aStop
+				rest.SetStartTime(bStop, false)
+				rest.SetStopTime(aStop)
+				p.reason(rest.ID, fmt.Sprintf("remainder of %d after %d", a.ID, b.ID))
+				remainders = append(remainders, rest)
+			}
+
+			a.SetStopTime(*b.Start)
+			p.reason(a.ID, fmt.Sprintf("trimmed to end when %d starts", b.ID))
+			changed = true
+			break
+		}
+	}
+
+	p.entries = append(p.entries, remainders...)
+	p.removeEmpty()
+	p.sort()
+	return
+}
+
+// MergeAdjacent merges consecutive stopped entries with the same description,
+// project, task, tags and billable flag that are separated by no more than
+// maxGap. It should be called before SplitAtMidnight, which would otherwise
+// have its pieces joined again.
+func (p *Plan) MergeAdjacent(maxGap time.Duration) {
+	var merged []toggl.TimeEntry
+	for _, e := range p.entries {
+		if n := len(merged); n > 0 {
+			last := &merged[n-1]
+			gap := e.Start.Sub(endTime(*last))
+			if !last.IsRunning() && !e.IsRunning() &&
+				gap >= 0 && gap <= maxGap && sameMetadata(*last, e) {
+				last.SetStopTime(endTime(e))
+				p.reason(last.ID, fmt.Sprintf("merged with %d", e.ID))
+				p.reason(e.ID, fmt.Sprintf("merged into %d", last.ID))
+				continue
+			}
+		}
+		merged = append(merged, e)
+	}
+	p.entries = merged
+}
+
+// SplitAtMidnight splits stopped entries that cross midnight in loc into one
+// entry per day.
+func (p *Plan) SplitAtMidnight(loc *time.Location) {
+	var split []toggl.TimeEntry
+	for _, e := range p.entries {
+		if e.IsRunning() {
+			split = append(split, e)
+			continue
+		}
+
+		stop := endTime(e)
+		for {
+			start := e.Start.In(loc)
+			y, m, d := start.Date()
+			midnight := time.Date(y, m, d+1, 0, 0, 0, 0, loc)
+			if !midnight.Before(stop) {
+				split = append(split, e)
+				break
+			}
+
+			e.SetStopTime(midnight)
+			p.reason(e.ID, "split at midnight")
+			split = append(split, e)
+
+			next := e.Copy()
+			next.ID = p.newID()
+			next.SetStartTime(midnight, false)
+			next.SetStopTime(stop)
+			p.reason(next.ID, fmt.Sprintf("split from %d at midnight", e.ID))
+			e = next
+		}
+	}
+	p.entries = split
+	p.sort()
+}
+
+// FillGaps adds placeholder entries for the untracked time during working
+// hours that lint reports as gaps with the given configuration.
+func (p *Plan) FillGaps(cfg lint.Config, placeholder Placeholder) {
+	cfg.MaxDuration = 0
+	cfg.RequireProject = false
+	cfg.RequireTags = false
+
+	for _, f := range lint.Check(p.entries, cfg) {
+		if f.Kind != lint.Gap {
+			continue
+		}
+		start, stop := f.Start, f.End
+		entry := toggl.TimeEntry{
+			ID:          p.newID(),
+			Wid:         placeholder.Wid,
+			Pid:         placeholder.Pid,
+			Description: placeholder.Description,
+			Tags:        append([]string{}, placeholder.Tags...),
+			Start:       &start,
+			Stop:        &stop,
+			Duration:    int64(stop.Sub(start) / time.Second),
+		}
+		p.reason(entry.ID, "fills gap")
+		p.entries = append(p.entries, entry)
+	}
+	p.sort()
+}
+
+// Edits returns the changes needed to turn the original entries into the
+// repaired ones. Deletes come first, then updates, then creates.
+func (p *Plan) Edits() []Edit {
+	var deletes, updates, creates []Edit
+	current := map[int]toggl.TimeEntry{}
+
+	for _, e := range p.entries {
+		current[e.ID] = e
+		after := e.Copy()
+
+		if e.ID < 0 {
+			creates = append(creates, Edit{Action: Create, After: &after, Reasons: p.reasons[e.ID]})
+			continue
+		}
+
+		before := p.originals[e.ID]
+		if !sameTimes(before, e) {
+			updates = append(updates, Edit{Action: Update, Before: &before, After: &after, Reasons: p.reasons[e.ID]})
+		}
+	}
+
+	for id, e := range p.originals {
+		if _, ok := current[id]; !ok {
+			before := e
+			deletes = append(deletes, Edit{Action: Delete, Before: &before, Reasons: p.reasons[id]})
+		}
+	}
+	sort.Slice(deletes, func(i, j int) bool {
+		return deletes[i].Before.Start.Before(*deletes[j].Before.Start)
+	})
+
+	return append(append(deletes, updates...), creates...)
+}
+
+// WriteDiff writes a preview of a plan's edits. Created entries are prefixed
+// with "+", updated entries with "~", and deleted ones with "-".
+func WriteDiff(w io.Writer, edits []Edit, loc *time.Location) error {
+	span := func(e *toggl.TimeEntry) string {
+		s := e.Start.In(loc).Format("2006-01-02 15:04")
+		if e.IsRunning() {
+			return s + "-(running)"
+		}
+		return s + "-" + endTime(*e).In(loc).Format("15:04")
+	}
+
+	for _, edit := range edits {
+		var line string
+		switch edit.Action {
+		case Create:
+			line = fmt.Sprintf("+ new %q %s", edit.After.Description, span(edit.After))
+		case Update:
+			line = fmt.Sprintf("~ %d %q %s -> %s", edit.Before.ID, edit.Before.Description, span(edit.Before), span(edit.After))
+		case Delete:
+			line = fmt.Sprintf("- %d %q %s", edit.Before.ID, edit.Before.Description, span(edit.Before))
+		}
+		if len(edit.Reasons) > 0 {
+			line += " (" + strings.Join(edit.Reasons, "; ") + ")"
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *Plan) newID() int {
+	id := p.nextID
+	p.nextID--
+	return id
+}
+
+func (p *Plan) reason(id int, reason string) {
+	p.reasons[id] = append(p.reasons[id], reason)
+}
+
+func (p *Plan) sort() {
+	sort.SliceStable(p.entries, func(i, j int) bool {
+		return p.entries[i].Start.Before(*p.entries[j].Start)
+	})
+}
+
+func (p *Plan) removeEmpty() {
+	var kept []toggl.TimeEntry
+	for _, e := range p.entries {
+		if e.IsRunning() || e.Duration > 0 {
+			kept = append(kept, e)
+		} else {
+			p.reason(e.ID, "no time left after trimming")
+		}
+	}
+	p.entries = kept
+}
+
+// endTime returns the time an entry ends, or the current time if it's
+// running.
+func endTime(e toggl.TimeEntry) time.Time {
+	if e.IsRunning() {
+		return time.Now()
+	}
+	if e.Stop != nil {
+		return *e.Stop
+	}
+	return e.Start.Add(time.Duration(e.Duration) * time.Second)
+}
+
+func sameMetadata(a, b toggl.TimeEntry) bool {
+	tags := func(e toggl.TimeEntry) []string {
+		t := append([]string{}, e.Tags...)
+		sort.Strings(t)
+		return t
+	}
+	return a.Description == b.Description &&
+		a.Billable == b.Billable &&
+		reflect.DeepEqual(a.Pid, b.Pid) &&
+		reflect.DeepEqual(a.Tid, b.Tid) &&
+		reflect.DeepEqual(tags(a), tags(b))
+}
+
+func sameTimes(a, b toggl.TimeEntry) bool {
+	return a.StartTime().Equal(b.StartTime()) &&
+		endTime(a).Equal(endTime(b)) &&
+		a.Duration == b.Duration
+}
@@ -0,0 +1,83 @@
+package repair
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jason0x43/go-toggl"
+)
+
+func TestTrimOverlapsWithoutStop(t *testing.T) {
+	at := func(hour, min int) *time.Time {
+		t := time.Date(2026, 10, 16, hour, min, 0, 0, time.UTC)
+		return &t
+	}
+
+	// the first entry has a duration but no stop time, and the second lies
+	// within it
+	entries := []toggl.TimeEntry{
+		{ID: 1, Description: "long", Start: at(9, 0), Duration: 3 * 3600},
+		{ID: 2, Description: "short", Start: at(10, 0), Stop: at(11, 0), Duration: 3600},
+	}
+
+	plan := NewPlan(entries)
+	plan.TrimOverlaps()
+
+	got := plan.Entries()
+	want := []struct {
+		id          int
+		start, stop *time.Time
+	}{
+		{1, at(9, 0), at(10, 0)},
+		{2, at(10, 0), at(11, 0)},
+		{-1, at(11, 0), at(12, 0)},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(got), len(want))
+	}
+	for i, w := range want {
+		e := got[i]
+		if e.ID != w.id || !e.Start.Equal(*w.start) || !endTime(e).Equal(*w.stop) ||
+			e.Duration != int64(w.stop.Sub(*w.start)/time.Second) {
+			t.Errorf("entry %d is %d %v-%v (%ds), want %d %v-%v",
+				i, e.ID, e.Start, endTime(e), e.Duration, w.id, w.start, w.stop)
+		}
+	}
+}
+
+func TestMergeBeforeSplit(t *testing.T) {
+	loc := time.UTC
+	at := func(day, hour int) *time.Time {
+		t := time.Date(2026, 10, day, hour, 0, 0, 0, loc)
+		return &t
+	}
+
+	entries := []toggl.TimeEntry{
+		{ID: 1, Description: "deploy", Start: at(16, 22), Stop: at(16, 23), Duration: 3600},
+		{ID: 2, Description: "deploy", Start: at(16, 23), Stop: at(17, 2), Duration: 3 * 3600},
+	}
+
+	plan := NewPlan(entries)
+	plan.MergeAdjacent(time.Minute)
+	plan.SplitAtMidnight(loc)
+
+	got := plan.Entries()
+	if len(got) != 2 {
+		t.Fatalf("got %d entries, want 2", len(got))
+	}
+	if got[0].ID != 1 || !got[0].Start.Equal(*at(16, 22)) || !got[0].Stop.Equal(*at(17, 0)) {
+		t.Errorf("first entry is %d %v-%v", got[0].ID, got[0].Start, got[0].Stop)
+	}
+	if got[1].ID >= 0 || !got[1].Start.Equal(*at(17, 0)) || !got[1].Stop.Equal(*at(17, 2)) {
+		t.Errorf("second entry is %d %v-%v", got[1].ID, got[1].Start, got[1].Stop)
+	}
+
+	edits := plan.Edits()
+	actions := map[Action]int{}
+	for _, edit := range edits {
+		actions[edit.Action]++
+	}
+	if actions[Delete] != 1 || actions[Update] != 1 || actions[Create] != 1 {
+		t.Errorf("unexpected edits %v", actions)
+	}
+}
@@ -0,0 +1,157 @@
+/*
+Package rounding rounds tracked time the way Toggl's reports do.
+
+A Rule rounds durations up, down, or to the nearest multiple of a number of
+minutes. Rounding can be applied to each time entry, which is what Toggl does
+when a report is requested with rounding enabled, or to the total time per day,
+per project, or per project per day.
+*/
+package rounding
+
+import (
+	"time"
+
+	"github.com/jason0x43/go-toggl"
+)
+
+// Direction is the direction durations are rounded in.
+type Direction int
+
+// Rounding directions. The values match those of Workspace.Rounding.
+const (
+	Down    Direction = -1
+	Nearest Direction = 0
+	Up      Direction = 1
+)
+
+// Scope determines what rounding is applied to.
+type Scope int
+
+// Rounding scopes
+const (
+	// PerEntry rounds each time entry's duration.
+	PerEntry Scope = iota
+
+	// PerDay rounds the total duration of each day.
+	PerDay
+
+	// PerProject rounds the total duration of each project.
+	PerProject
+
+	// PerDayProject rounds the total duration of each project on each day.
+	PerDayProject
+)
+
+// Rule is a rounding rule.
+type Rule struct {
+	Direction Direction
+
+	// Minutes is the rounding interval. Rounding is disabled if it's zero.
+	Minutes int
+
+	Scope Scope
+}
+
+// FromWorkspace returns the per-entry rounding rule configured for a
+// workspace.
+func FromWorkspace(workspace toggl.Workspace) Rule {
+	direction := Nearest
+	switch {
+	case workspace.Rounding < 0:
+		direction = Down
+	case workspace.Rounding > 0:
+		direction = Up
+	}
+	return Rule{Direction: direction, Minutes: workspace.RoundingMinutes}
+}
+
+// Enabled returns true if the rule changes durations.
+func (r Rule) Enabled() bool {
+	return r.Minutes > 0
+}
+
+// Round rounds a duration. Durations are first truncated to whole seconds,
+// the resolution Toggl tracks time at.
+func (r Rule) Round(d time.Duration) time.Duration {
+	d = d.Truncate(time.Second)
+	if !r.Enabled() {
+		return d
+	}
+
+	unit := time.Duration(r.Minutes) * time.Minute
+	switch r.Direction {
+	case Down:
+		return d.Truncate(unit)
+	case Up:
+		if d%unit == 0 {
+			return d
+		}
+		return d.Truncate(unit) + unit
+	default:
+		return d.Round(unit)
+	}
+}
+
+// Key identifies a group of entries that are rounded together. Fields that
+// aren't part of the rule's scope are zero.
+type Key struct {
+	// Day is the day entries started on, as YYYY-MM-DD.
+	Day string
+
+	// Pid is the project ID, or 0 for entries without a project.
+	Pid int
+}
+
+// Totals rounds the durations of time entries according to the rule's scope
+// and returns the rounded total for each group. Running entries are counted up
+// to now. Days are determined in loc.
+func (r Rule) Totals(entries []toggl.TimeEntry, loc *time.Location) map[Key]time.Duration {
+	now := time.Now()
+	raw := map[Key]time.Duration{}
+	totals := map[Key]time.Duration{}
+
+	for _, e := range entries {
+		if e.Start == nil {
+			continue
+		}
+
+		d := time.Duration(e.Duration) * time.Second
+		if e.IsRunning() {
+			d = now.Sub(*e.Start)
+		}
+
+		var key Key
+		if r.Scope == PerDay || r.Scope == PerDayProject {
+			key.Day = e.Start.In(loc).Format("2006-01-02")
+		}
+		if (r.Scope == PerProject || r.Scope == PerDayProject) && e.Pid != nil {
+			key.Pid = *e.Pid
+		}
+
+		if r.Scope == PerEntry {
+			totals[key] += r.Round(d)
+		} else {
+			raw[key] += d
+		}
+	}
+
+	for key, d := range raw {
+		totals[key] = r.Round(d)
+	}
+
+	return totals
+}
+
+// Total returns the sum of the rounded totals of time entries.
+func (r Rule) Total(entries []toggl.TimeEntry, loc *time.Location) time.Duration {
+	var total time.Duration
+	for _, d := range r.Totals(entries, loc) {
+		total += d
+	}
+	return total
+}
+
+// EntryDuration returns the rounded duration of a single stopped time entry.
+func (r Rule) EntryDuration(entry toggl.TimeEntry) time.Duration {
+	return r.Round(time.Duration(entry.Duration) * time.Second)
+}
@@ -0,0 +1,128 @@
+package rounding
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jason0x43/go-toggl"
+)
+
+func TestRound(t *testing.T) {
+	tests := []struct {
+		rule  Rule
+		input time.Duration
+		want  time.Duration
+	}{
+		{Rule{Direction: Up, Minutes: 0}, 7*time.Minute + 1500*time.Millisecond, 7*time.Minute + time.Second},
+		{Rule{Direction: Down, Minutes: 15}, 29 * time.Minute, 15 * time.Minute},
+		{Rule{Direction: Down, Minutes: 15}, 30 * time.Minute, 30 * time.Minute},
+		{Rule{Direction: Down, Minutes: 15}, 14*time.Minute + 59*time.Second, 0},
+		{Rule{Direction: Up, Minutes: 15}, time.Second, 15 * time.Minute},
+		{Rule{Direction: Up, Minutes: 15}, 30 * time.Minute, 30 * time.Minute},
+		{Rule{Direction: Up, Minutes: 15}, 30*time.Minute + 500*time.Millisecond, 30 * time.Minute},
+		{Rule{Direction: Up, Minutes: 6}, 61 * time.Minute, 66 * time.Minute},
+		{Rule{Direction: Nearest, Minutes: 15}, 7*time.Minute + 29*time.Second, 0},
+		{Rule{Direction: Nearest, Minutes: 15}, 7*time.Minute + 30*time.Second, 15 * time.Minute},
+		{Rule{Direction: Nearest, Minutes: 15}, 52 * time.Minute, 45 * time.Minute},
+		{Rule{Direction: Nearest, Minutes: 60}, 90 * time.Minute, 2 * time.Hour},
+	}
+
+	for _, test := range tests {
+		if got := test.rule.Round(test.input); got != test.want {
+			t.Errorf("%+v.Round(%v) = %v, want %v", test.rule, test.input, got, test.want)
+		}
+	}
+}
+
+func TestFromWorkspace(t *testing.T) {
+	tests := []struct {
+		rounding int
+		want     Direction
+	}{
+		{-1, Down},
+		{0, Nearest},
+		{1, Up},
+	}
+
+	for _, test := range tests {
+		rule := FromWorkspace(toggl.Workspace{Rounding: test.rounding, RoundingMinutes: 15})
+		if rule.Direction != test.want || rule.Minutes != 15 || rule.Scope != PerEntry {
+			t.Errorf("FromWorkspace with rounding %d = %+v", test.rounding, rule)
+		}
+	}
+}
+
+func TestTotals(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	entry := func(day, hour, minutes int, pid int) toggl.TimeEntry {
+		start := time.Date(2026, 10, day, hour, 0, 0, 0, loc)
+		e := toggl.TimeEntry{Start: &start, Duration: int64(minutes * 60)}
+		if pid != 0 {
+			e.Pid = &pid
+		}
+		return e
+	}
+	// the last entry starts on the 17th in UTC but on the 16th in New York
+	entries := []toggl.TimeEntry{
+		entry(16, 9, 10, 1),
+		entry(16, 10, 10, 1),
+		entry(16, 11, 10, 2),
+		entry(16, 12, 10, 0),
+		entry(16, 22, 10, 1),
+	}
+
+	tests := []struct {
+		scope Scope
+		want  map[Key]time.Duration
+	}{
+		{PerEntry, map[Key]time.Duration{
+			{}: 75 * time.Minute,
+		}},
+		{PerDay, map[Key]time.Duration{
+			{Day: "2026-10-16"}: 60 * time.Minute,
+		}},
+		{PerProject, map[Key]time.Duration{
+			{Pid: 1}: 30 * time.Minute,
+			{Pid: 2}: 15 * time.Minute,
+			{}:       15 * time.Minute,
+		}},
+		{PerDayProject, map[Key]time.Duration{
+			{Day: "2026-10-16", Pid: 1}: 30 * time.Minute,
+			{Day: "2026-10-16", Pid: 2}: 15 * time.Minute,
+			{Day: "2026-10-16"}:         15 * time.Minute,
+		}},
+	}
+
+	for _, test := range tests {
+		rule := Rule{Direction: Up, Minutes: 15, Scope: test.scope}
+		got := rule.Totals(entries, loc)
+		if len(got) != len(test.want) {
+			t.Errorf("scope %d: got totals %v, want %v", test.scope, got, test.want)
+			continue
+		}
+		var sum time.Duration
+		for key, d := range test.want {
+			if got[key] != d {
+				t.Errorf("scope %d: total for %+v = %v, want %v", test.scope, key, got[key], d)
+			}
+			sum += d
+		}
+		if total := rule.Total(entries, loc); total != sum {
+			t.Errorf("scope %d: Total = %v, want %v", test.scope, total, sum)
+		}
+	}
+}
+
+func TestTotalsRunning(t *testing.T) {
+	start := time.Now().Add(-20 * time.Minute)
+	entries := []toggl.TimeEntry{{Start: &start, Duration: -start.Unix()}}
+
+	rule := Rule{Direction: Up, Minutes: 15}
+	if total := rule.Total(entries, time.UTC); total != 30*time.Minute {
+		t.Errorf("running entry total = %v, want 30m", total)
+	}
+}
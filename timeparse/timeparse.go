@@ -0,0 +1,367 @@
+/*
+Package timeparse reads times and durations written the way people type them,
+such as "9:30", "yesterday 14:00", "-15m", "30 minutes ago", "last friday
+17:00", "1h30m", "1.5h" or "90".
+
+Times are interpreted in a Parser's time zone, which is usually the zone of the
+user's Toggl account:
+
+	p := timeparse.ForAccount(account)
+	start, err := p.Time("yesterday 9:00")
+	start, stop, err := p.Range("9:00-10:30")
+	d, err := timeparse.Duration("1h30m")
+*/
+package timeparse
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jason0x43/go-toggl"
+)
+
+// Parser parses times relative to a time zone and a current time.
+type Parser struct {
+	// Location is the time zone times are interpreted in. It defaults to the
+	// local time zone.
+	Location *time.Location
+
+	// Now is the time relative times are interpreted from. It defaults to
+	// the current time.
+	Now time.Time
+}
+
+// ForAccount returns a Parser that interprets times in an account's time
+// zone, or in the local time zone if the account's zone is unknown.
+func ForAccount(account toggl.Account) Parser {
+	p := Parser{Location: time.Local}
+	if account.Timezone != "" {
+		if loc, err := time.LoadLocation(account.Timezone); err == nil {
+			p.Location = loc
+		}
+	}
+	return p
+}
+
+var unitDurations = map[string]time.Duration{
+	"s": time.Second, "sec": time.Second, "secs": time.Second, "second": time.Second, "seconds": time.Second,
+	"m": time.Minute, "min": time.Minute, "mins": time.Minute, "minute": time.Minute, "minutes": time.Minute,
+	"h": time.Hour, "hr": time.Hour, "hrs": time.Hour, "hour": time.Hour, "hours": time.Hour,
+	"d": 24 * time.Hour, "day": 24 * time.Hour, "days": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour, "week": 7 * 24 * time.Hour, "weeks": 7 * 24 * time.Hour,
+}
+
+var absoluteLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+}
+
+/*
+Time parses a time. It accepts
+
+	now
+	9:30, 9:30:15, 14, 9am, 9:30 pm, noon, midnight    today at that time
+	-15m, +1h, -90                                      an offset from now
+	30 minutes ago, an hour ago, in 2 hours             an offset from now
+	today, yesterday, tomorrow                          a day, at midnight
+	friday, fri, last friday, next friday               a day, at midnight
+	2026-10-16                                          a day, at midnight
+	yesterday 14:00, last friday 17:00                  a day and a time
+	2026-10-16 17:00, 2026-10-16T17:00:00Z              a full time
+
+A bare day of the week is the latest such day up to today, "last" refers to
+one before today and "next" to one after today. Offsets are written as
+durations; see Duration.
+*/
+func (p Parser) Time(s string) (time.Time, error) {
+	t, _, err := p.parseTime(s)
+	return t, err
+}
+
+// Range parses a pair of times separated by "-" or "to", such as "9:00-10:30"
+// or "yesterday 22:00 to 01:00". An end without a day is on the start's day,
+// or on the next day if it would otherwise be before the start.
+func (p Parser) Range(s string) (start, end time.Time, err error) {
+	s = strings.TrimSpace(s)
+	lower := strings.ToLower(s)
+
+	// the separator may also appear in dates and offsets, so each possible
+	// split is tried from the right
+	for i := len(s) - 1; i > 0; i-- {
+		var left, right string
+		switch {
+		case s[i] == '-':
+			left, right = s[:i], s[i+1:]
+		case strings.HasPrefix(lower[i:], " to "):
+			left, right = s[:i], s[i+4:]
+		default:
+			continue
+		}
+
+		if start, _, err = p.parseTime(left); err != nil {
+			continue
+		}
+		endParser := Parser{Location: p.Location, Now: start}
+		var hasDay bool
+		if end, hasDay, err = endParser.parseTime(right); err != nil {
+			continue
+		}
+		if !hasDay && end.Before(start) {
+			end = end.AddDate(0, 0, 1)
+		}
+		if !end.After(start) {
+			return start, end, fmt.Errorf("end of %q is not after its start", s)
+		}
+		return start, end, nil
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("invalid time range %q", s)
+}
+
+// Duration parses a duration such as "1h30m", "1.5h", "1:30", "90 minutes",
+// "2 hours 15 minutes" or "90". A bare number is a number of minutes.
+// Negative durations aren't accepted.
+func Duration(s string) (time.Duration, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	d, ok := parseDuration(s)
+	if !ok {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d, nil
+}
+
+func parseDuration(s string) (time.Duration, bool) {
+	if s == "" || strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		return 0, false
+	}
+
+	if minutes, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Duration(minutes * float64(time.Minute)), true
+	}
+
+	if parts := strings.Split(s, ":"); len(parts) == 2 {
+		h, herr := strconv.Atoi(parts[0])
+		m, merr := strconv.Atoi(parts[1])
+		if herr == nil && merr == nil && h >= 0 && m >= 0 && m < 60 && len(parts[1]) == 2 {
+			return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute, true
+		}
+		return 0, false
+	}
+
+	if d, err := time.ParseDuration(strings.Join(strings.Fields(s), "")); err == nil {
+		return d, true
+	}
+
+	// a list of amounts and units, such as "2 hours 15 minutes"
+	fields := strings.Fields(s)
+	if len(fields) == 0 || len(fields)%2 != 0 {
+		return 0, false
+	}
+	var total time.Duration
+	for i := 0; i < len(fields); i += 2 {
+		var amount float64
+		switch fields[i] {
+		case "a", "an":
+			amount = 1
+		default:
+			var err error
+			if amount, err = strconv.ParseFloat(fields[i], 64); err != nil || amount < 0 {
+				return 0, false
+			}
+		}
+		unit, ok := unitDurations[fields[i+1]]
+		if !ok {
+			return 0, false
+		}
+		total += time.Duration(amount * float64(unit))
+	}
+	return total, true
+}
+
+// parseTime parses a time, and reports whether it included a day.
+func (p Parser) parseTime(s string) (t time.Time, hasDay bool, err error) {
+	loc := p.Location
+	if loc == nil {
+		loc = time.Local
+	}
+	now := p.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	now = now.In(loc)
+	invalid := fmt.Errorf("invalid time %q", s)
+
+	s = strings.ToLower(strings.TrimSpace(s))
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return t, false, invalid
+	}
+
+	if s == "now" {
+		return now, true, nil
+	}
+	for _, layout := range absoluteLayouts {
+		if t, err := time.ParseInLocation(layout, strings.ToUpper(s), loc); err == nil {
+			return t, true, nil
+		}
+	}
+
+	switch {
+	case s[0] == '-' || s[0] == '+':
+		d, ok := parseDuration(strings.TrimSpace(s[1:]))
+		if !ok {
+			return t, false, invalid
+		}
+		if s[0] == '-' {
+			d = -d
+		}
+		return now.Add(d), true, nil
+	case fields[len(fields)-1] == "ago":
+		d, ok := parseDuration(strings.Join(fields[:len(fields)-1], " "))
+		if !ok {
+			return t, false, invalid
+		}
+		return now.Add(-d), true, nil
+	case fields[0] == "in":
+		d, ok := parseDuration(strings.Join(fields[1:], " "))
+		if !ok {
+			return t, false, invalid
+		}
+		return now.Add(d), true, nil
+	}
+
+	day, n := parseDay(fields, now)
+	hasDay = n > 0
+	fields = fields[n:]
+	if len(fields) == 0 {
+		if !hasDay {
+			return t, false, invalid
+		}
+		return day, true, nil
+	}
+
+	// "9:30 pm" and "9:30pm" are the same, but nothing else may follow the
+	// time of day
+	clock := fields[0]
+	if len(fields) == 2 && isMeridiem(fields[1]) {
+		clock += fields[1]
+	} else if len(fields) > 1 {
+		return t, false, invalid
+	}
+	h, m, sec, ok := parseClock(clock)
+	if !ok {
+		return t, false, invalid
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(), h, m, sec, 0, loc), hasDay, nil
+}
+
+// parseDay parses a day at the start of fields, returning the start of the
+// day and the number of fields used. If there's no day, today is returned
+// with 0 fields used.
+func parseDay(fields []string, now time.Time) (time.Time, int) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	switch fields[0] {
+	case "today":
+		return today, 1
+	case "yesterday":
+		return today.AddDate(0, 0, -1), 1
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), 1
+	}
+
+	if day, err := time.ParseInLocation("2006-01-02", fields[0], now.Location()); err == nil {
+		return day, 1
+	}
+
+	modifier := ""
+	name := fields[0]
+	if (name == "last" || name == "next") && len(fields) > 1 {
+		modifier, name = name, fields[1]
+	}
+	wd, ok := parseWeekday(name)
+	if !ok {
+		return today, 0
+	}
+
+	switch modifier {
+	case "last":
+		days := (int(today.Weekday())-int(wd)+6)%7 + 1
+		return today.AddDate(0, 0, -days), 2
+	case "next":
+		days := (int(wd)-int(today.Weekday())+6)%7 + 1
+		return today.AddDate(0, 0, days), 2
+	default:
+		days := (int(today.Weekday()) - int(wd) + 7) % 7
+		return today.AddDate(0, 0, -days), 1
+	}
+}
+
+func parseWeekday(s string) (time.Weekday, bool) {
+	for wd := time.Sunday; wd <= time.Saturday; wd++ {
+		name := strings.ToLower(wd.String())
+		if s == name || s == name[:3] {
+			return wd, true
+		}
+	}
+	return 0, false
+}
+
+func isMeridiem(s string) bool {
+	return s == "am" || s == "pm" || s == "a" || s == "p"
+}
+
+// parseClock parses a time of day such as "9", "9:30", "09:30:15", "9am",
+// "9:30pm", "noon" or "midnight".
+func parseClock(s string) (h, m, sec int, ok bool) {
+	switch s {
+	case "noon":
+		return 12, 0, 0, true
+	case "midnight":
+		return 0, 0, 0, true
+	}
+
+	suffix := ""
+	for _, sfx := range []string{"am", "pm", "a", "p"} {
+		if strings.HasSuffix(s, sfx) {
+			suffix, s = sfx[:1], strings.TrimSuffix(s, sfx)
+			break
+		}
+	}
+
+	parts := strings.Split(s, ":")
+	if len(parts) > 3 {
+		return 0, 0, 0, false
+	}
+	values := make([]int, 3)
+	for i, part := range parts {
+		v, err := strconv.Atoi(part)
+		if err != nil || v < 0 || (i > 0 && len(part) != 2) {
+			return 0, 0, 0, false
+		}
+		values[i] = v
+	}
+	h, m, sec = values[0], values[1], values[2]
+	if m > 59 || sec > 59 {
+		return 0, 0, 0, false
+	}
+
+	switch suffix {
+	case "":
+		return h, m, sec, h < 24
+	case "a":
+		if h < 1 || h > 12 {
+			return 0, 0, 0, false
+		}
+		return h % 12, m, sec, true
+	default:
+		if h < 1 || h > 12 {
+			return 0, 0, 0, false
+		}
+		return h%12 + 12, m, sec, true
+	}
+}
@@ -0,0 +1,155 @@
+package timeparse
+
+import (
+	"testing"
+	"time"
+)
+
+// testParser returns a parser for Sunday, 18 October 2026 at 15:04 in New
+// York.
+func testParser(t *testing.T) (Parser, *time.Location) {
+	t.Helper()
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	return Parser{Location: loc, Now: time.Date(2026, 10, 18, 15, 4, 0, 0, loc)}, loc
+}
+
+func TestTime(t *testing.T) {
+	p, loc := testParser(t)
+	at := func(month time.Month, day, hour, min int) time.Time {
+		return time.Date(2026, month, day, hour, min, 0, 0, loc)
+	}
+
+	tests := []struct {
+		input string
+		want  time.Time
+	}{
+		{"now", p.Now},
+		{"9:30", at(10, 18, 9, 30)},
+		{"9:30 pm", at(10, 18, 21, 30)},
+		{"9am", at(10, 18, 9, 0)},
+		{"12am", at(10, 18, 0, 0)},
+		{"noon", at(10, 18, 12, 0)},
+		{"yesterday 14:00", at(10, 17, 14, 0)},
+		{"Yesterday 2pm", at(10, 17, 14, 0)},
+		{"-15m", at(10, 18, 14, 49)},
+		{"+1h", at(10, 18, 16, 4)},
+		{"-90", at(10, 18, 13, 34)},
+		{"30 minutes ago", at(10, 18, 14, 34)},
+		{"an hour ago", at(10, 18, 14, 4)},
+		{"in 2 hours", at(10, 18, 17, 4)},
+		{"last friday 17:00", at(10, 16, 17, 0)},
+		{"friday", at(10, 16, 0, 0)},
+		{"sunday", at(10, 18, 0, 0)},
+		{"last sunday", at(10, 11, 0, 0)},
+		{"next mon 9am", at(10, 19, 9, 0)},
+		{"2026-10-01", at(10, 1, 0, 0)},
+		{"2026-10-01 08:15", at(10, 1, 8, 15)},
+		{"2026-10-01T12:15:00Z", at(10, 1, 8, 15)},
+	}
+
+	for _, test := range tests {
+		got, err := p.Time(test.input)
+		if err != nil {
+			t.Errorf("Time(%q) failed: %v", test.input, err)
+			continue
+		}
+		if !got.Equal(test.want) {
+			t.Errorf("Time(%q) = %v, want %v", test.input, got, test.want)
+		}
+	}
+}
+
+func TestTimeInvalid(t *testing.T) {
+	p, _ := testParser(t)
+
+	for _, input := range []string{"", "foo", "25:00", "9:5", "13pm", "1 2:00", "9:30 tomorrow", "yesterday 9 30", "5 parsecs ago"} {
+		if got, err := p.Time(input); err == nil {
+			t.Errorf("Time(%q) = %v, want an error", input, got)
+		}
+	}
+}
+
+func TestDuration(t *testing.T) {
+	tests := []struct {
+		input string
+		want  time.Duration
+	}{
+		{"1h30m", 90 * time.Minute},
+		{"1h 30m", 90 * time.Minute},
+		{"1.5h", 90 * time.Minute},
+		{"90", 90 * time.Minute},
+		{"1:30", 90 * time.Minute},
+		{"90 minutes", 90 * time.Minute},
+		{"2 hours 15 minutes", 135 * time.Minute},
+		{"an hour", time.Hour},
+	}
+
+	for _, test := range tests {
+		got, err := Duration(test.input)
+		if err != nil {
+			t.Errorf("Duration(%q) failed: %v", test.input, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("Duration(%q) = %v, want %v", test.input, got, test.want)
+		}
+	}
+
+	for _, input := range []string{"", "-5m", "x", "1:5", "2 parsecs"} {
+		if got, err := Duration(input); err == nil {
+			t.Errorf("Duration(%q) = %v, want an error", input, got)
+		}
+	}
+}
+
+func TestRange(t *testing.T) {
+	p, loc := testParser(t)
+	at := func(month time.Month, day, hour, min int) time.Time {
+		return time.Date(2026, month, day, hour, min, 0, 0, loc)
+	}
+
+	tests := []struct {
+		input      string
+		start, end time.Time
+	}{
+		{"9:00-10:30", at(10, 18, 9, 0), at(10, 18, 10, 30)},
+		{"9:00 - 10:30", at(10, 18, 9, 0), at(10, 18, 10, 30)},
+		{"yesterday 9:00 to 10:00", at(10, 17, 9, 0), at(10, 17, 10, 0)},
+		{"2026-10-01 9:00-10:30", at(10, 1, 9, 0), at(10, 1, 10, 30)},
+		{"22:00-1:00", at(10, 18, 22, 0), at(10, 19, 1, 0)},
+		{"yesterday 23:30 to 00:15", at(10, 17, 23, 30), at(10, 18, 0, 15)},
+		{"2026-10-01-2026-10-02", at(10, 1, 0, 0), at(10, 2, 0, 0)},
+	}
+
+	for _, test := range tests {
+		start, end, err := p.Range(test.input)
+		if err != nil {
+			t.Errorf("Range(%q) failed: %v", test.input, err)
+			continue
+		}
+		if !start.Equal(test.start) || !end.Equal(test.end) {
+			t.Errorf("Range(%q) = %v, %v, want %v, %v", test.input, start, end, test.start, test.end)
+		}
+	}
+}
+
+func TestRangeInvalid(t *testing.T) {
+	p, _ := testParser(t)
+
+	tests := []string{
+		// the end has a day and isn't after the start
+		"2026-10-02 9:00 to 2026-10-01 10:00",
+		"9:00 to 9:00 today",
+		"9:00",
+		"1 2:00-3:00",
+		"lunch",
+	}
+	for _, input := range tests {
+		if start, end, err := p.Range(input); err == nil {
+			t.Errorf("Range(%q) = %v, %v, want an error", input, start, end)
+		}
+	}
+}
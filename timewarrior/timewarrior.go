@@ -0,0 +1,281 @@
+/*
+Package timewarrior converts between Toggl time entries and Timewarrior's
+data files.
+
+Timewarrior stores intervals in monthly files (such as 2024-01.data) in its
+data directory, one per line:
+
+	inc 20240102T090000Z - 20240102T103000Z # acme "code review" # "Reviewed PR 12"
+
+Timewarrior has no notion of projects, so a time entry's project name is
+written as the interval's first tag and its description as the annotation.
+When reading, a first tag that names a known project is used as the project.
+*/
+package timewarrior
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jason0x43/go-toggl"
+	"github.com/jason0x43/go-toggl/importer"
+)
+
+const timeLayout = "20060102T150405Z"
+
+// Interval is a tracked Timewarrior interval. A zero End indicates an open
+// interval.
+type Interval struct {
+	Start      time.Time
+	End        time.Time
+	Tags       []string
+	Annotation string
+}
+
+// String returns the interval formatted as a data file line.
+func (iv Interval) String() string {
+	var b strings.Builder
+	b.WriteString("inc ")
+	b.WriteString(iv.Start.UTC().Format(timeLayout))
+	if !iv.End.IsZero() {
+		b.WriteString(" - ")
+		b.WriteString(iv.End.UTC().Format(timeLayout))
+	}
+	if len(iv.Tags) > 0 || iv.Annotation != "" {
+		b.WriteString(" #")
+		for _, tag := range iv.Tags {
+			b.WriteString(" ")
+			b.WriteString(quote(tag))
+		}
+	}
+	if iv.Annotation != "" {
+		b.WriteString(" # ")
+		b.WriteString(`"` + escaper.Replace(iv.Annotation) + `"`)
+	}
+	return b.String()
+}
+
+// ParseLine parses a single data file line.
+func ParseLine(line string) (Interval, error) {
+	var iv Interval
+	tokens, err := tokenize(line)
+	if err != nil {
+		return iv, err
+	}
+	if len(tokens) < 2 || tokens[0].text != "inc" {
+		return iv, fmt.Errorf("invalid interval %q", line)
+	}
+
+	if iv.Start, err = time.Parse(timeLayout, tokens[1].text); err != nil {
+		return iv, err
+	}
+
+	rest := tokens[2:]
+	if len(rest) >= 2 && rest[0].text == "-" && !rest[0].quoted {
+		if iv.End, err = time.Parse(timeLayout, rest[1].text); err != nil {
+			return iv, err
+		}
+		rest = rest[2:]
+	}
+
+	if len(rest) == 0 {
+		return iv, nil
+	}
+	if rest[0].text != "#" || rest[0].quoted {
+		return iv, fmt.Errorf("invalid interval %q", line)
+	}
+
+	rest = rest[1:]
+	for i, t := range rest {
+		if t.text == "#" && !t.quoted {
+			var parts []string
+			for _, a := range rest[i+1:] {
+				parts = append(parts, a.text)
+			}
+			iv.Annotation = strings.Join(parts, " ")
+			break
+		}
+		iv.Tags = append(iv.Tags, t.text)
+	}
+
+	return iv, nil
+}
+
+// Read reads the intervals in a data file. Blank lines are ignored.
+func Read(r io.Reader) ([]Interval, error) {
+	var intervals []Interval
+	scanner := bufio.NewScanner(r)
+	n := 0
+	for scanner.Scan() {
+		n++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		iv, err := ParseLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", n, err)
+		}
+		intervals = append(intervals, iv)
+	}
+	return intervals, scanner.Err()
+}
+
+var dataFilePattern = regexp.MustCompile(`^\d{4}-\d{2}\.data$`)
+
+// ReadDir reads the intervals in all monthly data files in a Timewarrior data
+// directory, ordered by start time.
+func ReadDir(dir string) ([]Interval, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var intervals []Interval
+	for _, f := range files {
+		if f.IsDir() || !dataFilePattern.MatchString(f.Name()) {
+			continue
+		}
+		file, err := os.Open(filepath.Join(dir, f.Name()))
+		if err != nil {
+			return nil, err
+		}
+		list, err := Read(file)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", f.Name(), err)
+		}
+		intervals = append(intervals, list...)
+	}
+
+	sort.Slice(intervals, func(i, j int) bool {
+		return intervals[i].Start.Before(intervals[j].Start)
+	})
+	return intervals, nil
+}
+
+// Write writes intervals in data file format.
+func Write(w io.Writer, intervals []Interval) error {
+	for _, iv := range intervals {
+		if _, err := fmt.Fprintln(w, iv); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FromTimeEntries converts time entries into intervals. Running entries become
+// open intervals.
+func FromTimeEntries(entries []toggl.TimeEntry, index toggl.Index) []Interval {
+	var intervals []Interval
+	for _, e := range entries {
+		if e.Start == nil {
+			continue
+		}
+
+		iv := Interval{Start: *e.Start, Annotation: e.Description}
+		if !e.IsRunning() {
+			iv.End = e.Start.Add(time.Duration(e.Duration) * time.Second)
+		}
+		if project := index.ProjectName(e.Pid); project != "" {
+			iv.Tags = append(iv.Tags, project)
+		}
+		iv.Tags = append(iv.Tags, e.Tags...)
+
+		intervals = append(intervals, iv)
+	}
+	return intervals
+}
+
+// Records converts closed intervals into import records. If an interval's
+// first tag is the name of one of the account's projects it is used as the
+// record's project. Intervals without an annotation use their remaining tags,
+// joined by spaces, as a description.
+func Records(intervals []Interval, account toggl.Account) []importer.Record {
+	projects := map[string]bool{}
+	for _, p := range account.Projects {
+		projects[strings.ToLower(p.Name)] = true
+	}
+
+	var records []importer.Record
+	for i, iv := range intervals {
+		if iv.End.IsZero() {
+			continue
+		}
+
+		record := importer.Record{
+			Line:        i + 1,
+			Description: iv.Annotation,
+			Start:       iv.Start,
+			End:         iv.End,
+		}
+
+		tags := iv.Tags
+		if len(tags) > 0 && projects[strings.ToLower(tags[0])] {
+			record.Project = tags[0]
+			tags = tags[1:]
+		}
+		record.Tags = append([]string{}, tags...)
+		if record.Description == "" {
+			record.Description = strings.Join(tags, " ")
+		}
+
+		records = append(records, record)
+	}
+	return records
+}
+
+// support /////////////////////////////////////////////////////////////
+
+type token struct {
+	text   string
+	quoted bool
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+func quote(s string) string {
+	if s == "" || strings.ContainsAny(s, " \t\"#\\") {
+		return `"` + escaper.Replace(s) + `"`
+	}
+	return s
+}
+
+func tokenize(line string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(line) {
+		switch {
+		case line[i] == ' ' || line[i] == '\t':
+			i++
+		case line[i] == '"':
+			var b strings.Builder
+			i++
+			for ; i < len(line) && line[i] != '"'; i++ {
+				if line[i] == '\\' && i+1 < len(line) {
+					i++
+				}
+				b.WriteByte(line[i])
+			}
+			if i >= len(line) {
+				return nil, fmt.Errorf("unterminated quote in %q", line)
+			}
+			i++
+			tokens = append(tokens, token{text: b.String(), quoted: true})
+		default:
+			start := i
+			for i < len(line) && line[i] != ' ' && line[i] != '\t' {
+				i++
+			}
+			tokens = append(tokens, token{text: line[start:i]})
+		}
+	}
+	return tokens, nil
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/jason0x43/go-toggl"
+)
+
+var accountCommand = &command{
+	name:  "account",
+	short: "display account information",
+	run:   runAccount,
+}
+
+func runAccount(cmd *command, session *toggl.Session, args []string) error {
+	account, err := session.GetAccount()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(&account, "", "    ")
+	if err != nil {
+		return err
+	}
+	fmt.Println("account:", string(data))
+	return nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jason0x43/go-toggl"
+	"github.com/jason0x43/go-toggl/timeparse"
+)
+
+var addCommand = &command{
+	name:  "add",
+	usage: "[-project NAME|ID] [-tag TAGS] [-billable] [-workspace ID] DESCRIPTION START-END",
+	short: "add a stopped time entry",
+	run:   runAdd,
+}
+
+func runAdd(cmd *command, session *toggl.Session, args []string) error {
+	fs := newFlagSet(cmd)
+	project := fs.String("project", "", "project name or ID")
+	tags := fs.String("tag", "", "comma separated tags")
+	billable := fs.Bool("billable", false, "mark the entry billable")
+	wid := fs.Int("workspace", 0, "workspace ID (defaults to the project's or the first workspace)")
+	fs.Parse(args)
+
+	if fs.NArg() < 2 {
+		fs.Usage()
+		return fmt.Errorf("a description and a time range are required")
+	}
+
+	account, err := session.GetAccount()
+	if err != nil {
+		return err
+	}
+	parser := timeparse.ForAccount(account)
+
+	// the range may span several arguments, as in "yesterday 9:00-10:30", so
+	// the longest trailing range is used
+	words := fs.Args()
+	var start, end time.Time
+	n := 1
+	for ; n < len(words); n++ {
+		if start, end, err = parser.Range(strings.Join(words[n:], " ")); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	entry, err := newEntry(account, strings.Join(words[:n], " "), *project, *tags, *billable, *wid)
+	if err != nil {
+		return err
+	}
+	entry.Start = &start
+	entry.Stop = &end
+	entry.Duration = int64(end.Sub(start) / time.Second)
+
+	created, err := session.CreateTimeEntry(entry)
+	if err != nil {
+		return err
+	}
+	d := time.Duration(created.Duration) * time.Second
+	fmt.Println(describeTimer(created, d, toggl.NewIndex(account)))
+	return nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/jason0x43/go-toggl"
+	"github.com/jason0x43/go-toggl/daemon"
+)
+
+var daemonCommand = &command{
+	name:  "daemon",
+	usage: "[-socket PATH] [-interval DURATION] [-rate DURATION]",
+	short: "share one session with local clients over a socket",
+	run:   runDaemon,
+}
+
+func runDaemon(cmd *command, session *toggl.Session, args []string) error {
+	server := daemon.NewServer(session)
+
+	fs := newFlagSet(cmd)
+	socket := fs.String("socket", daemon.DefaultSocketPath(), "path of the Unix socket to listen on")
+	fs.DurationVar(&server.Interval, "interval", server.Interval, "how often to check the running timer")
+	fs.DurationVar(&server.MinRequestInterval, "rate", server.MinRequestInterval, "shortest time between requests to Toggl")
+	fs.Parse(args)
+
+	l, err := daemon.Listen(*socket)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(*socket)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Fprintf(os.Stderr, "listening on %s\n", *socket)
+	return server.Serve(ctx, l)
+}
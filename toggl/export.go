@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jason0x43/go-toggl"
+	"github.com/jason0x43/go-toggl/export"
+	"github.com/jason0x43/go-toggl/ical"
+	"github.com/jason0x43/go-toggl/invoice"
+	"github.com/jason0x43/go-toggl/orgmode"
+	"github.com/jason0x43/go-toggl/timewarrior"
+	"github.com/jason0x43/go-toggl/watson"
+)
+
+var exportCommand = &command{
+	name:  "export",
+	usage: "[-format csv|ics|org|timeclock|timewarrior|watson] [-since DATE] [-until DATE] [-report] [-workspace ID] [-rate RATE] [-o FILE]",
+	short: "export time entries",
+	run:   runExport,
+}
+
+func runExport(cmd *command, session *toggl.Session, args []string) error {
+	fs := newFlagSet(cmd)
+	format := fs.String("format", "csv", "output format (csv, ics, org, timeclock, timewarrior or watson)")
+	fs.StringVar(format, "to", "csv", "alias for -format")
+	since := fs.String("since", "", "first day to export (YYYY-MM-DD)")
+	until := fs.String("until", "", "last day to export (YYYY-MM-DD)")
+	report := fs.Bool("report", false, "export the workspace's detailed report rather than your own entries")
+	wid := fs.Int("workspace", 0, "workspace ID for -report (defaults to the first workspace)")
+	rate := fs.Float64("rate", 0, "hourly rate for billable entries (defaults to the project or workspace rate)")
+	output := fs.String("o", "", "output file (defaults to stdout)")
+	fs.Parse(args)
+
+	account, err := session.GetAccount()
+	if err != nil {
+		return err
+	}
+	loc := accountLocation(account)
+	start, end, err := parseRange(*since, *until, loc)
+	if err != nil {
+		return err
+	}
+
+	index := toggl.NewIndex(account)
+	opts := export.Options{
+		User:     account.Fullname,
+		Email:    account.Email,
+		Location: loc,
+	}
+	// only CSV files have amounts
+	if *format == "csv" {
+		if opts.HourlyRate, err = hourlyRates(session, account, *rate); err != nil {
+			return err
+		}
+	}
+
+	currency := ""
+	if workspace, err := workspaceID(account, *wid); err == nil {
+		currency = index.Workspaces[workspace].DefaultCurrency
+	}
+
+	out, err := openOutput(*output)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if *report {
+		workspace, err := workspaceID(account, *wid)
+		if err != nil {
+			return err
+		}
+		entries, err := getDetailedTimeEntries(session, workspace, start, end)
+		if err != nil {
+			return err
+		}
+		rows := export.DetailedTimeEntryRows(entries, index, opts)
+
+		switch *format {
+		case "csv":
+			return export.WriteCSV(out, rows, currency)
+		case "timeclock":
+			return export.WriteTimeclock(out, rows)
+		default:
+			return fmt.Errorf("format %q is not supported for reports", *format)
+		}
+	}
+
+	entries, err := session.GetTimeEntries(start, end)
+	if err != nil {
+		return err
+	}
+
+	switch *format {
+	case "csv":
+		return export.WriteCSV(out, export.TimeEntryRows(entries, index, opts), currency)
+	case "timeclock":
+		return export.WriteTimeclock(out, export.TimeEntryRows(entries, index, opts))
+	case "ics":
+		return ical.WriteCalendar(out, ical.FromTimeEntries(entries, index))
+	case "org":
+		return orgmode.Write(out, entries, index, loc)
+	case "timewarrior":
+		return timewarrior.Write(out, timewarrior.FromTimeEntries(entries, index))
+	case "watson":
+		return watson.Write(out, watson.FromTimeEntries(entries, index))
+	default:
+		return fmt.Errorf("unknown format %q", *format)
+	}
+}
+
+// hourlyRates returns a function giving the hourly rate for a user's time on
+// a project: rate if it's set, or else the user's rate for the project, the
+// project's rate or its workspace's default rate. Entries without a project
+// use the first workspace's rate, and entries without a user are the
+// account's own.
+func hourlyRates(session *toggl.Session, account toggl.Account, rate float64) (func(pid, uid int) float64, error) {
+	if rate != 0 {
+		return func(int, int) float64 { return rate }, nil
+	}
+
+	rates := map[int]invoice.Rates{}
+	for _, w := range account.Workspaces {
+		users, err := session.GetProjectUsers(w.ID)
+		if err != nil {
+			return nil, fmt.Errorf("Error getting project users: %v", err)
+		}
+		rates[w.ID] = invoice.NewRates(w, account.Projects, users)
+	}
+	index := toggl.NewIndex(account)
+
+	return func(pid, uid int) float64 {
+		wid, _ := workspaceID(account, 0)
+		if p, ok := index.Projects[pid]; ok {
+			wid = p.Wid
+		}
+		if uid == 0 {
+			uid = account.ID
+		}
+		return rates[wid].Rate(pid, uid)
+	}, nil
+}
+
+// getDetailedTimeEntries retrieves all pages of a detailed report.
+func getDetailedTimeEntries(
+	session *toggl.Session,
+	workspace int,
+	start, end time.Time,
+) ([]toggl.DetailedTimeEntry, error) {
+	since := start.Format(dateFormat)
+	until := end.AddDate(0, 0, -1).Format(dateFormat)
+
+	var entries []toggl.DetailedTimeEntry
+	for page := 1; ; page++ {
+		report, err := session.GetDetailedReport(workspace, since, until, page)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, report.Data...)
+		if len(report.Data) == 0 || len(entries) >= report.TotalCount {
+			return entries, nil
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/jason0x43/go-toggl"
+	"github.com/jason0x43/go-toggl/exporter"
+)
+
+var exporterCommand = &command{
+	name:  "exporter",
+	usage: "[-listen ADDRESS] [-max-age DURATION] [-workspace ID]",
+	short: "serve tracked time as Prometheus metrics",
+	run:   runExporter,
+}
+
+func runExporter(cmd *command, session *toggl.Session, args []string) error {
+	metrics := exporter.NewAPIMetrics()
+	session.Use(metrics.Middleware)
+	handler := exporter.New(session, metrics)
+
+	fs := newFlagSet(cmd)
+	listen := fs.String("listen", ":9746", "address to serve metrics on")
+	fs.DurationVar(&handler.MaxAge, "max-age", handler.MaxAge, "how long to reuse data loaded from Toggl")
+	fs.IntVar(&handler.Workspace, "workspace", 0, "report the time of every member of this workspace")
+	fs.Parse(args)
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", handler)
+
+	fmt.Fprintf(os.Stderr, "serving metrics on %s/metrics\n", *listen)
+	return http.ListenAndServe(*listen, mux)
+}
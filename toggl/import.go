@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/jason0x43/go-toggl"
+	"github.com/jason0x43/go-toggl/ical"
+	"github.com/jason0x43/go-toggl/importer"
+	"github.com/jason0x43/go-toggl/orgmode"
+	"github.com/jason0x43/go-toggl/timewarrior"
+	"github.com/jason0x43/go-toggl/watson"
+)
+
+var importCommand = &command{
+	name:  "import",
+	usage: "[-format csv|ics|org|timewarrior|watson] [-map FIELD=COLUMN,...] [-rules FILE] [-workspace ID] [-marker tag|description] [-allow-overlaps] [-dry-run] [-force] FILE",
+	short: "import time entries",
+	run:   runImport,
+}
+
+func runImport(cmd *command, session *toggl.Session, args []string) error {
+	fs := newFlagSet(cmd)
+	format := fs.String("format", "csv", "input format (csv, ics, org, timewarrior or watson)")
+	fs.StringVar(format, "from", "csv", "alias for -format")
+	mapping := fs.String("map", "", "column mapping overrides, such as description=Notes,project=Job")
+	rulesFile := fs.String("rules", "", "JSON file of event rules (ics) or headline path rules (org)")
+	wid := fs.Int("workspace", 0, "workspace ID (defaults to the first workspace)")
+	marker := fs.String("marker", "tag", "where to store import markers (tag or description)")
+	allowOverlaps := fs.Bool("allow-overlaps", false, "import entries that overlap existing ones")
+	dryRun := fs.Bool("dry-run", false, "show what would be imported without creating entries")
+	force := fs.Bool("force", false, "import valid rows even if some rows have errors")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	account, err := session.GetAccount()
+	if err != nil {
+		return err
+	}
+	workspace, err := workspaceID(account, *wid)
+	if err != nil {
+		return err
+	}
+
+	imp := &importer.Importer{
+		Session:       session,
+		Account:       account,
+		Workspace:     workspace,
+		AllowOverlaps: *allowOverlaps,
+	}
+	switch *marker {
+	case "tag":
+		imp.Marker = importer.TagMarker
+	case "description":
+		imp.Marker = importer.DescriptionMarker
+	default:
+		return fmt.Errorf("unknown marker style %q", *marker)
+	}
+
+	var records []importer.Record
+	var issues []importer.Issue
+	loc := accountLocation(account)
+
+	if *format == "timewarrior" {
+		// Timewarrior data may be read from a whole data directory
+		if info, err := os.Stat(fs.Arg(0)); err == nil && info.IsDir() {
+			intervals, err := timewarrior.ReadDir(fs.Arg(0))
+			if err != nil {
+				return err
+			}
+			return importRecords(imp, timewarrior.Records(intervals, account), nil, *dryRun, *force)
+		}
+	}
+
+	file, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	switch *format {
+	case "csv":
+		csvFormat, err := importer.TogglFormat.WithColumns(*mapping)
+		if err != nil {
+			return err
+		}
+		csvFormat.Location = loc
+		if records, issues, err = importer.ReadCSV(file, csvFormat); err != nil {
+			return err
+		}
+	case "ics":
+		var rules ical.RuleSet
+		if *rulesFile != "" {
+			if err = readJSONFile(*rulesFile, &rules); err != nil {
+				return err
+			}
+			if err = rules.Compile(); err != nil {
+				return err
+			}
+		}
+		events, err := ical.ReadCalendar(file, loc)
+		if err != nil {
+			return err
+		}
+		records = ical.Records(events, rules)
+	case "org":
+		var mapping orgmode.PathMapping
+		if *rulesFile != "" {
+			if err = readJSONFile(*rulesFile, &mapping); err != nil {
+				return err
+			}
+		}
+		clocks, err := orgmode.Read(file, loc)
+		if err != nil {
+			return err
+		}
+		records = orgmode.Records(clocks, mapping)
+	case "timewarrior":
+		intervals, err := timewarrior.Read(file)
+		if err != nil {
+			return err
+		}
+		records = timewarrior.Records(intervals, account)
+	case "watson":
+		frames, err := watson.Read(file)
+		if err != nil {
+			return err
+		}
+		records = watson.Records(frames, account)
+	default:
+		return fmt.Errorf("unknown format %q", *format)
+	}
+
+	return importRecords(imp, records, issues, *dryRun, *force)
+}
+
+// importRecords plans an import, prints the plan, and applies it unless this
+// is a dry run or the plan has errors.
+func importRecords(
+	imp *importer.Importer,
+	records []importer.Record,
+	issues []importer.Issue,
+	dryRun, force bool,
+) error {
+	plan, err := imp.Plan(records)
+	if err != nil {
+		return err
+	}
+	plan.Issues = append(issues, plan.Issues...)
+
+	if err := plan.WriteDiff(os.Stdout); err != nil {
+		return err
+	}
+	if dryRun {
+		return nil
+	}
+	if plan.HasErrors() && !force {
+		return fmt.Errorf("input has errors; fix them or use -force to import the valid rows")
+	}
+
+	created, err := imp.Apply(plan)
+	fmt.Printf("created %d entries\n", len(created))
+	return err
+}
+
+func readJSONFile(path string, v interface{}) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return json.NewDecoder(file).Decode(v)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jason0x43/go-toggl"
+	"github.com/jason0x43/go-toggl/lint"
+)
+
+var lintCommand = &command{
+	name:  "lint",
+	usage: "[-since DATE] [-until DATE] [-hours HH:MM-HH:MM] [-min-gap DURATION] [-max DURATION] [-require-tags] [-allow-no-project]",
+	short: "report problems with time entries",
+	run:   runLint,
+}
+
+func runLint(cmd *command, session *toggl.Session, args []string) error {
+	cfg := lint.DefaultConfig()
+
+	fs := newFlagSet(cmd)
+	since := fs.String("since", "", "first day to check (YYYY-MM-DD)")
+	until := fs.String("until", "", "last day to check (YYYY-MM-DD)")
+	hours := fs.String("hours", "09:00-17:00", "working hours")
+	fs.DurationVar(&cfg.MinGap, "min-gap", cfg.MinGap, "shortest gap to report (0 to disable)")
+	fs.DurationVar(&cfg.MaxDuration, "max", cfg.MaxDuration, "longest allowed entry (0 to disable)")
+	fs.BoolVar(&cfg.RequireTags, "require-tags", false, "report entries without tags")
+	allowNoProject := fs.Bool("allow-no-project", false, "don't report entries without a project")
+	fs.Parse(args)
+
+	cfg.RequireProject = !*allowNoProject
+	if err := parseHours(*hours, &cfg); err != nil {
+		return err
+	}
+
+	account, err := session.GetAccount()
+	if err != nil {
+		return err
+	}
+	cfg.Location = accountLocation(account)
+	start, end, err := parseRange(*since, *until, cfg.Location)
+	if err != nil {
+		return err
+	}
+	cfg.Start, cfg.End = start, end
+
+	entries, err := session.GetTimeEntries(start, end)
+	if err != nil {
+		return err
+	}
+
+	findings := lint.Check(entries, cfg)
+	for _, f := range findings {
+		fmt.Printf(
+			"%s  %-10s  %s\n",
+			f.Start.In(cfg.Location).Format("2006-01-02 15:04"),
+			f.Kind,
+			f.Message,
+		)
+	}
+
+	if len(findings) > 0 {
+		return fmt.Errorf("found %d problems", len(findings))
+	}
+	return nil
+}
+
+// parseHours parses working hours given as HH:MM-HH:MM.
+func parseHours(s string, cfg *lint.Config) error {
+	parts := strings.Split(s, "-")
+	if len(parts) != 2 {
+		return fmt.Errorf("invalid working hours %q", s)
+	}
+
+	offsets := make([]time.Duration, 2)
+	for i, p := range parts {
+		t, err := time.Parse("15:04", strings.TrimSpace(p))
+		if err != nil {
+			return fmt.Errorf("invalid working hours %q", s)
+		}
+		offsets[i] = time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	}
+
+	cfg.WorkStart, cfg.WorkEnd = offsets[0], offsets[1]
+	return nil
+}
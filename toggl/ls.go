@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jason0x43/go-toggl"
+	"github.com/jason0x43/go-toggl/query"
+)
+
+var lsCommand = &command{
+	name:  "ls",
+	usage: "[-since DATE] [-until DATE] [-project IDS] [-no-project] [-client IDS] [-tag TAGS] [-not-tag TAGS] [-billable yes|no] [-match REGEXP] [-running] [-min DURATION] [-max DURATION] [-report] [-workspace ID] [QUERY...]",
+	short: "list time entries",
+	run:   runLs,
+}
+
+func runLs(cmd *command, session *toggl.Session, args []string) error {
+	fs := newFlagSet(cmd)
+	since := fs.String("since", "", "first day to list (YYYY-MM-DD)")
+	until := fs.String("until", "", "last day to list (YYYY-MM-DD)")
+	projects := fs.String("project", "", "comma separated project IDs")
+	noProject := fs.Bool("no-project", false, "list entries without a project")
+	clients := fs.String("client", "", "comma separated client IDs")
+	tags := fs.String("tag", "", "comma separated tags entries must have")
+	notTags := fs.String("not-tag", "", "comma separated tags entries must not have")
+	billable := fs.String("billable", "", "list only billable (yes) or non-billable (no) entries")
+	match := fs.String("match", "", "regular expression descriptions must match")
+	running := fs.Bool("running", false, "list only the running entry")
+	minDuration := fs.Duration("min", 0, "shortest entry to list")
+	maxDuration := fs.Duration("max", 0, "longest entry to list")
+	report := fs.Bool("report", false, "list entries from the detailed report")
+	wid := fs.Int("workspace", 0, "workspace ID (defaults to the first workspace)")
+	fs.Parse(args)
+
+	account, err := session.GetAccount()
+	if err != nil {
+		return err
+	}
+	loc := accountLocation(account)
+	index := toggl.NewIndex(account)
+
+	q := query.New().
+		WithIndex(index).
+		At(time.Now().In(loc)).
+		MinDuration(*minDuration).
+		MaxDuration(*maxDuration)
+
+	if *since != "" || *until != "" {
+		start, end, err := parseRange(*since, *until, loc)
+		if err != nil {
+			return err
+		}
+		q = q.Between(start, end)
+	}
+
+	ids, err := parseIDs(*projects)
+	if err != nil {
+		return err
+	}
+	q = q.Project(ids...)
+	if *noProject {
+		q = q.NoProject()
+	}
+
+	if ids, err = parseIDs(*clients); err != nil {
+		return err
+	}
+	q = q.Client(ids...)
+
+	for _, tag := range splitList(*tags) {
+		q = q.Tag(tag)
+	}
+	for _, tag := range splitList(*notTags) {
+		q = q.NotTag(tag)
+	}
+
+	switch *billable {
+	case "":
+	case "yes":
+		q = q.Billable(true)
+	case "no":
+		q = q.Billable(false)
+	default:
+		return fmt.Errorf("-billable must be yes or no")
+	}
+
+	if *match != "" {
+		re, err := regexp.Compile(*match)
+		if err != nil {
+			return err
+		}
+		q = q.DescriptionMatches(re)
+	}
+	if *running {
+		q = q.Running()
+	}
+
+	if fs.NArg() > 0 {
+		parser := query.Parser{Index: index, Location: loc}
+		if q, err = parser.Apply(q, strings.Join(fs.Args(), " ")); err != nil {
+			if serr, ok := err.(*query.SyntaxError); ok {
+				fmt.Fprintln(os.Stderr, serr.Context())
+			}
+			return err
+		}
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	defer w.Flush()
+
+	if *report {
+		workspace, err := workspaceID(account, *wid)
+		if err != nil {
+			return err
+		}
+		entries, err := q.FetchDetailed(session, workspace)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			listEntry(w, e.Start, e.End, time.Duration(e.Duration)*time.Millisecond, e.Project, e.Description, e.Tags, loc)
+		}
+		return nil
+	}
+
+	if *wid != 0 {
+		q = q.Workspace(*wid)
+	}
+	entries, err := q.Fetch(session)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		d := time.Duration(e.Duration) * time.Second
+		if e.IsRunning() {
+			d = time.Since(e.StartTime())
+		}
+		project := index.ProjectName(e.Pid)
+		if project == "" {
+			project = e.ProjectName
+		}
+		listEntry(w, e.Start, e.Stop, d, project, e.Description, e.Tags, loc)
+	}
+	return nil
+}
+
+// listEntry writes a line describing a time entry.
+func listEntry(
+	w *tabwriter.Writer,
+	start, stop *time.Time,
+	d time.Duration,
+	project, description string,
+	tags []string,
+	loc *time.Location,
+) {
+	span := "-"
+	if start != nil {
+		span = start.In(loc).Format("2006-01-02 15:04") + "-"
+		if stop != nil {
+			span += stop.In(loc).Format("15:04")
+		}
+	}
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", span, d.Round(time.Second), project, description, strings.Join(tags, ", "))
+}
+
+// parseIDs parses a comma separated list of IDs.
+func parseIDs(s string) ([]int, error) {
+	var ids []int
+	for _, item := range splitList(s) {
+		id, err := strconv.Atoi(item)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ID %q", item)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// splitList splits a comma separated list, ignoring empty items.
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -1,35 +1,154 @@
 /*
-The toggl command will display a user's Toggl account information.
+The toggl command provides access to a user's Toggl account.
 
 Usage:
 
+	toggl [-token API_TOKEN] [-debug] [-dry-run] [-journal FILE] COMMAND [arguments]
 	toggl API_TOKEN
 
-The API token can be retrieved from a user's account information page at toggl.com.
+The API token can be retrieved from a user's account information page at
+toggl.com. It may also be given in the TOGGL_API_TOKEN environment variable.
+Run with only a token to display the user's account information. With
+-dry-run, commands read data from Toggl as usual but print the changes they
+would make instead of making them. With -journal, or when the TOGGL_JOURNAL
+environment variable is set, every change is recorded so that it can be
+reverted with the undo command.
+
+Times given to start, stop and add are read in the account's time zone and may
+be written as 9:30, yesterday 14:00, -15m, 30 minutes ago or last friday 17:00.
+
+The commands are:
+
+	account    display account information
+	add        add a stopped time entry
+	daemon     share one session with local clients over a socket
+	export     export time entries
+	exporter   serve tracked time as Prometheus metrics
+	import     import time entries
+	lint       report problems with time entries
+	ls         list time entries
+	repair     fix overlaps, gaps and split entries
+	start      start a timer
+	status     show the running timer
+	stop       stop the running timer
+	undo       revert changes recorded in the journal
 */
 package main
 
 import (
-	"encoding/json"
+	"flag"
+	"fmt"
 	"os"
 
 	"github.com/jason0x43/go-toggl"
 )
 
+// command is a toggl subcommand.
+type command struct {
+	name  string
+	usage string
+	short string
+	run   func(cmd *command, session *toggl.Session, args []string) error
+}
+
+var commands = []*command{
+	accountCommand,
+	addCommand,
+	daemonCommand,
+	exportCommand,
+	exporterCommand,
+	importCommand,
+	lintCommand,
+	lsCommand,
+	repairCommand,
+	startCommand,
+	statusCommand,
+	stopCommand,
+	undoCommand,
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: %s [-token API_TOKEN] [-debug] [-dry-run] [-journal FILE] COMMAND [arguments]\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s API_TOKEN\n\ncommands:\n", os.Args[0])
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", c.name, c.short)
+	}
+}
+
+func findCommand(name string) *command {
+	for _, c := range commands {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
 func main() {
-	if len(os.Args) != 2 || os.Args[1] == "-h" || os.Args[1] == "--help" {
-		println("usage:", os.Args[0], "API_TOKEN")
-		return
+	token := flag.String("token", os.Getenv("TOGGL_API_TOKEN"), "Toggl API token")
+	debug := flag.Bool("debug", false, "log API requests to stderr")
+	dryRun := flag.Bool("dry-run", false, "print changes instead of sending them to Toggl")
+	journal := flag.String("journal", os.Getenv("TOGGL_JOURNAL"), "file to record changes in so they can be undone")
+	flag.Usage = usage
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	cmd := findCommand(args[0])
+	if cmd == nil {
+		if len(args) != 1 {
+			usage()
+			os.Exit(2)
+		}
+		// Legacy form: toggl API_TOKEN
+		*token = args[0]
+		cmd = accountCommand
+		args = []string{cmd.name}
+	}
+
+	if *token == "" {
+		fmt.Fprintln(os.Stderr, "An API token is required")
+		os.Exit(2)
 	}
 
-	session := toggl.OpenSession(os.Args[1])
+	if !*debug {
+		toggl.DisableLog()
+	}
+
+	session := toggl.OpenSession(*token)
+	session.SetDryRun(*dryRun)
+	if *journal != "" {
+		j, err := toggl.OpenJournal(*journal)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		session.SetJournal(j)
+	}
+	err := cmd.run(cmd, &session, args[1:])
 
-	account, err := session.GetAccount()
+	if *dryRun {
+		for _, req := range session.DryRunPlan() {
+			fmt.Fprintf(os.Stderr, "dry run: %s %s %s\n", req.Method, req.URL, req.Body)
+		}
+	}
 	if err != nil {
-		println(err.Error())
-		return
+		fmt.Fprintf(os.Stderr, "%s: %v\n", cmd.name, err)
+		os.Exit(1)
 	}
+}
 
-	data, err := json.MarshalIndent(&account, "", "    ")
-	println("account:", string(data))
+// newFlagSet creates a flag set for a command that prints the command's usage
+// on error.
+func newFlagSet(cmd *command) *flag.FlagSet {
+	fs := flag.NewFlagSet(cmd.name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: %s %s %s\n", os.Args[0], cmd.name, cmd.usage)
+		fs.PrintDefaults()
+	}
+	return fs
 }
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jason0x43/go-toggl"
+	"github.com/jason0x43/go-toggl/lint"
+	"github.com/jason0x43/go-toggl/repair"
+)
+
+var repairCommand = &command{
+	name:  "repair",
+	usage: "[-since DATE] [-until DATE] [-trim] [-merge DURATION] [-split] [-fill PROJECT_ID] [-hours HH:MM-HH:MM] [-apply] [-undo-log FILE] | -undo FILE",
+	short: "fix overlaps, gaps and split entries",
+	run:   runRepair,
+}
+
+func runRepair(cmd *command, session *toggl.Session, args []string) error {
+	cfg := lint.DefaultConfig()
+
+	fs := newFlagSet(cmd)
+	since := fs.String("since", "", "first day to repair (YYYY-MM-DD)")
+	until := fs.String("until", "", "last day to repair (YYYY-MM-DD)")
+	trim := fs.Bool("trim", false, "trim overlapping entries")
+	merge := fs.Duration("merge", -1, "merge matching entries separated by at most this long")
+	split := fs.Bool("split", false, "split entries at midnight")
+	fill := fs.Int("fill", 0, "fill gaps in working hours with entries for this project")
+	fillDescription := fs.String("fill-description", "", "description of gap-filling entries")
+	hours := fs.String("hours", "09:00-17:00", "working hours")
+	fs.DurationVar(&cfg.MinGap, "min-gap", cfg.MinGap, "shortest gap to fill")
+	apply := fs.Bool("apply", false, "apply the edits instead of previewing them")
+	undoLog := fs.String("undo-log", "toggl-repair.undo", "file to record applied edits in")
+	undo := fs.String("undo", "", "revert the edits recorded in an undo log")
+	fs.Parse(args)
+
+	if *undo != "" {
+		return undoRepair(session, *undo)
+	}
+
+	if err := parseHours(*hours, &cfg); err != nil {
+		return err
+	}
+
+	account, err := session.GetAccount()
+	if err != nil {
+		return err
+	}
+	cfg.Location = accountLocation(account)
+	start, end, err := parseRange(*since, *until, cfg.Location)
+	if err != nil {
+		return err
+	}
+	cfg.Start, cfg.End = start, end
+
+	entries, err := session.GetTimeEntries(start, end)
+	if err != nil {
+		return err
+	}
+
+	// entries are merged before they're split so that merging can't join
+	// the pieces of a split entry
+	plan := repair.NewPlan(entries)
+	if *trim {
+		plan.TrimOverlaps()
+	}
+	if *merge >= 0 {
+		plan.MergeAdjacent(*merge)
+	}
+	if *split {
+		plan.SplitAtMidnight(cfg.Location)
+	}
+	if *fill != 0 {
+		pid := *fill
+		project, ok := toggl.NewIndex(account).Projects[pid]
+		if !ok {
+			return fmt.Errorf("unknown project %d", pid)
+		}
+		plan.FillGaps(cfg, repair.Placeholder{
+			Wid:         project.Wid,
+			Pid:         &pid,
+			Description: *fillDescription,
+		})
+	}
+
+	edits := plan.Edits()
+	if len(edits) == 0 {
+		fmt.Println("nothing to repair")
+		return nil
+	}
+	if err := repair.WriteDiff(os.Stdout, edits, cfg.Location); err != nil {
+		return err
+	}
+	if !*apply {
+		return nil
+	}
+
+	// a dry run mustn't replace the last real undo log with made up IDs
+	if session.IsDryRun() {
+		_, err := repair.Apply(session, edits, io.Discard)
+		return err
+	}
+
+	log, err := os.OpenFile(*undoLog, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer log.Close()
+
+	applied, err := repair.Apply(session, edits, log)
+	fmt.Printf("applied %d of %d edits; undo with -undo %s\n", len(applied), len(edits), *undoLog)
+	return err
+}
+
+func undoRepair(session *toggl.Session, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	edits, err := repair.ReadUndoLog(f)
+	if err != nil {
+		return err
+	}
+	if err := repair.Undo(session, edits); err != nil {
+		return err
+	}
+
+	fmt.Printf("reverted %d edits\n", len(edits))
+	return nil
+}
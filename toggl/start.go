@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jason0x43/go-toggl"
+	"github.com/jason0x43/go-toggl/timeparse"
+)
+
+var startCommand = &command{
+	name:  "start",
+	usage: "[-at TIME] [-project NAME|ID] [-tag TAGS] [-billable] [-workspace ID] DESCRIPTION",
+	short: "start a timer",
+	run:   runStart,
+}
+
+var stopCommand = &command{
+	name:  "stop",
+	usage: "[-at TIME]",
+	short: "stop the running timer",
+	run:   runStop,
+}
+
+func runStart(cmd *command, session *toggl.Session, args []string) error {
+	fs := newFlagSet(cmd)
+	at := fs.String("at", "", "when the timer started, such as 9:30 or -15m (defaults to now)")
+	project := fs.String("project", "", "project name or ID")
+	tags := fs.String("tag", "", "comma separated tags")
+	billable := fs.Bool("billable", false, "mark the entry billable")
+	wid := fs.Int("workspace", 0, "workspace ID (defaults to the project's or the first workspace)")
+	fs.Parse(args)
+
+	account, err := session.GetAccount()
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	if *at != "" {
+		if start, err = timeparse.ForAccount(account).Time(*at); err != nil {
+			return err
+		}
+	}
+
+	entry, err := newEntry(account, strings.Join(fs.Args(), " "), *project, *tags, *billable, *wid)
+	if err != nil {
+		return err
+	}
+	entry.Start = &start
+	entry.Duration = -1
+
+	started, err := session.CreateTimeEntry(entry)
+	if err != nil {
+		return err
+	}
+	fmt.Println(describeTimer(started, time.Since(started.StartTime()), toggl.NewIndex(account)))
+	return nil
+}
+
+func runStop(cmd *command, session *toggl.Session, args []string) error {
+	fs := newFlagSet(cmd)
+	at := fs.String("at", "", "when the timer stopped, such as 17:00 or 10 minutes ago (defaults to now)")
+	fs.Parse(args)
+
+	account, err := session.GetAccount()
+	if err != nil {
+		return err
+	}
+	current, err := session.GetCurrentTimeEntry()
+	if err != nil {
+		return err
+	}
+	if current.ID == 0 {
+		return fmt.Errorf("no timer running")
+	}
+
+	var stopped toggl.TimeEntry
+	if *at == "" {
+		stopped, err = session.StopTimeEntry(current)
+	} else {
+		var stop time.Time
+		if stop, err = timeparse.ForAccount(account).Time(*at); err != nil {
+			return err
+		}
+		if !stop.After(current.StartTime()) {
+			return fmt.Errorf("stop time %s is before the timer started", stop.Format(time.RFC3339))
+		}
+		// SetStopTime only changes stopped entries
+		current.Duration = 0
+		if err = current.SetStopTime(stop); err != nil {
+			return err
+		}
+		stopped, err = session.UpdateTimeEntry(current)
+	}
+	if err != nil {
+		return err
+	}
+
+	d := time.Duration(stopped.Duration) * time.Second
+	fmt.Println(describeTimer(stopped, d, toggl.NewIndex(account)))
+	return nil
+}
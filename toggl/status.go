@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/jason0x43/go-toggl"
+)
+
+var statusCommand = &command{
+	name:  "status",
+	usage: "[-watch] [-interval DURATION]",
+	short: "show the running timer",
+	run:   runStatus,
+}
+
+func runStatus(cmd *command, session *toggl.Session, args []string) error {
+	fs := newFlagSet(cmd)
+	watch := fs.Bool("watch", false, "keep printing changes to the running timer")
+	interval := fs.Duration("interval", time.Minute, "how often to check the running timer when watching")
+	fs.Parse(args)
+
+	account, err := session.GetAccount()
+	if err != nil {
+		return err
+	}
+	index := toggl.NewIndex(account)
+
+	if !*watch {
+		current, err := session.GetCurrentTimeEntry()
+		if err != nil {
+			return err
+		}
+		if current.ID == 0 {
+			fmt.Println("no timer running")
+			return nil
+		}
+		fmt.Println(describeTimer(current, time.Since(current.StartTime()), index))
+		return nil
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	for event := range session.WatchCurrentTimeEntry(ctx, *interval) {
+		line := describeTimer(event.Entry, event.Elapsed, index)
+		switch event.Kind {
+		case toggl.TimerChanged:
+			line += " [" + strings.Join(event.Changed, ", ") + "]"
+		case toggl.TimerError:
+			line = event.Err.Error()
+		}
+		fmt.Printf("%s %-7s %s\n", time.Now().Format("15:04:05"), event.Kind, line)
+	}
+	return nil
+}
+
+// describeTimer returns a one line summary of a timer.
+func describeTimer(e toggl.TimeEntry, elapsed time.Duration, index toggl.Index) string {
+	s := fmt.Sprintf("%q", e.Description)
+	if e.Pid != nil {
+		s += " (" + index.ProjectName(e.Pid) + ")"
+	}
+	return s + " " + elapsed.Round(time.Second).String()
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/jason0x43/go-toggl"
+)
+
+var undoCommand = &command{
+	name:  "undo",
+	usage: "[-n COUNT] [-list]",
+	short: "revert changes recorded in the journal",
+	run:   runUndo,
+}
+
+func runUndo(cmd *command, session *toggl.Session, args []string) error {
+	fs := newFlagSet(cmd)
+	n := fs.Int("n", 1, "number of changes to revert")
+	list := fs.Bool("list", false, "list the recorded changes instead of reverting them")
+	fs.Parse(args)
+
+	journal := session.Journal()
+	if journal == nil {
+		return fmt.Errorf("no journal; use -journal FILE or set TOGGL_JOURNAL")
+	}
+
+	if *list {
+		entries, err := journal.Entries()
+		if err != nil {
+			return err
+		}
+		for i := len(entries) - 1; i >= 0; i-- {
+			printJournalEntry(entries[i])
+		}
+		return nil
+	}
+
+	undone, err := session.Undo(*n)
+	verb := "reverted "
+	if session.IsDryRun() {
+		verb = "would revert "
+	}
+	for _, entry := range undone {
+		fmt.Print(verb)
+		printJournalEntry(entry)
+	}
+	return err
+}
+
+func printJournalEntry(entry toggl.JournalEntry) {
+	fmt.Printf(
+		"%s %s %s %d (%s)\n",
+		entry.Time.Local().Format("2006-01-02 15:04:05"),
+		entry.Action,
+		entry.Kind,
+		entry.ID,
+		entry.Operation,
+	)
+}
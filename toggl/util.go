@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jason0x43/go-toggl"
+)
+
+const dateFormat = "2006-01-02"
+
+// accountLocation returns the location for an account's time zone, falling
+// back to the local time zone.
+func accountLocation(account toggl.Account) *time.Location {
+	if account.Timezone != "" {
+		if loc, err := time.LoadLocation(account.Timezone); err == nil {
+			return loc
+		}
+	}
+	return time.Local
+}
+
+// workspaceID returns wid if it's set, or the ID of the account's first
+// workspace.
+func workspaceID(account toggl.Account, wid int) (int, error) {
+	if wid != 0 {
+		return wid, nil
+	}
+	if len(account.Workspaces) == 0 {
+		return 0, fmt.Errorf("account has no workspaces")
+	}
+	return account.Workspaces[0].ID, nil
+}
+
+// parseRange parses a pair of dates given as YYYY-MM-DD. The returned range
+// runs from the start of since to the end of until. If since is empty the range
+// starts a week before until; if until is empty it ends today.
+func parseRange(since, until string, loc *time.Location) (start, end time.Time, err error) {
+	now := time.Now().In(loc)
+	end = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	if until != "" {
+		if end, err = time.ParseInLocation(dateFormat, until, loc); err != nil {
+			return
+		}
+	}
+	end = end.AddDate(0, 0, 1)
+
+	start = end.AddDate(0, 0, -7)
+	if since != "" {
+		if start, err = time.ParseInLocation(dateFormat, since, loc); err != nil {
+			return
+		}
+	}
+
+	if !start.Before(end) {
+		err = fmt.Errorf("start date must be before end date")
+	}
+	return
+}
+
+// openOutput returns a writer for path, or stdout if path is empty or "-".
+func openOutput(path string) (io.WriteCloser, error) {
+	if path == "" || path == "-" {
+		return nopCloser{os.Stdout}, nil
+	}
+	return os.Create(path)
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
+
+// findProject returns the project with an ID or, ignoring case, a name.
+func findProject(account toggl.Account, s string) (toggl.Project, error) {
+	id, _ := strconv.Atoi(s)
+	var found []toggl.Project
+	for _, p := range account.Projects {
+		if p.ID == id || strings.EqualFold(p.Name, s) {
+			found = append(found, p)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return toggl.Project{}, fmt.Errorf("unknown project %q", s)
+	case 1:
+		return found[0], nil
+	default:
+		return toggl.Project{}, fmt.Errorf("%d projects are named %q; use an ID", len(found), s)
+	}
+}
+
+// newEntry creates a time entry with a description and the project, tags and
+// billable flag given on the command line.
+func newEntry(account toggl.Account, description, project, tags string, billable bool, wid int) (toggl.TimeEntry, error) {
+	entry := toggl.TimeEntry{
+		Description: description,
+		Tags:        splitList(tags),
+		Billable:    billable,
+	}
+	if project != "" {
+		p, err := findProject(account, project)
+		if err != nil {
+			return entry, err
+		}
+		entry.Pid = &p.ID
+		entry.Wid = p.Wid
+		return entry, nil
+	}
+
+	var err error
+	entry.Wid, err = workspaceID(account, wid)
+	return entry, err
+}
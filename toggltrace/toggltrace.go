@@ -0,0 +1,126 @@
+/*
+Package toggltrace creates OpenTelemetry spans for Toggl API requests.
+
+Add the middleware to a session, and give the session the caller's context so
+API spans become children of the caller's span:
+
+	session.Use(toggltrace.Middleware())
+	entry, err := session.WithContext(ctx).StartTimeEntry("Reviews", wid)
+
+Each request gets a client span named for the Session method that made it,
+such as "toggl.StartTimeEntry", with attributes for the HTTP method, URL and
+status, the workspace ID, and the number of retries. The trace context is
+propagated to Toggl in the request headers.
+
+Retries are counted by middleware added after this one, such as toggl.Retry,
+so that a span covers every attempt at a request:
+
+	session.Use(toggltrace.Middleware(), toggl.Retry(3, time.Second))
+*/
+package toggltrace
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/jason0x43/go-toggl"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentationName is the name of the tracer used for spans.
+const InstrumentationName = "github.com/jason0x43/go-toggl/toggltrace"
+
+// Attribute keys
+const (
+	WorkspaceIDKey = attribute.Key("toggl.workspace_id")
+	RetryCountKey  = attribute.Key("toggl.retry_count")
+)
+
+var workspacePattern = regexp.MustCompile(`/workspaces/(\d+)(/|$)`)
+
+// Option configures the middleware.
+type Option func(*config)
+
+type config struct {
+	provider   trace.TracerProvider
+	propagator propagation.TextMapPropagator
+}
+
+// WithTracerProvider sets the tracer provider spans are created with. It
+// defaults to the global provider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.provider = provider
+	}
+}
+
+// WithPropagator sets the propagator used to add the trace context to request
+// headers. It defaults to the global propagator.
+func WithPropagator(propagator propagation.TextMapPropagator) Option {
+	return func(c *config) {
+		c.propagator = propagator
+	}
+}
+
+// Middleware returns session middleware that traces requests.
+func Middleware(opts ...Option) toggl.Middleware {
+	cfg := config{
+		provider:   otel.GetTracerProvider(),
+		propagator: otel.GetTextMapPropagator(),
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	tracer := cfg.provider.Tracer(InstrumentationName)
+
+	return func(next toggl.RoundTripFunc) toggl.RoundTripFunc {
+		return func(req *toggl.Request) (*toggl.Response, error) {
+			name := req.Operation
+			if name == "" {
+				name = "toggl " + req.Method
+			}
+
+			attrs := []attribute.KeyValue{
+				attribute.String("http.request.method", req.Method),
+				attribute.String("url.full", req.URL()),
+			}
+			if m := workspacePattern.FindStringSubmatch(req.Path); m != nil {
+				if wid, err := strconv.Atoi(m[1]); err == nil {
+					attrs = append(attrs, WorkspaceIDKey.Int(wid))
+				}
+			}
+
+			ctx, span := tracer.Start(
+				req.Context,
+				name,
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(attrs...),
+			)
+			defer span.End()
+
+			req.Context = ctx
+			cfg.propagator.Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+			resp, err := next(req)
+
+			span.SetAttributes(RetryCountKey.Int(req.Retries))
+			if resp != nil {
+				span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+			}
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			} else if resp != nil && resp.StatusCode >= http.StatusBadRequest {
+				span.SetStatus(codes.Error, fmt.Sprintf("HTTP %d", resp.StatusCode))
+			}
+
+			return resp, err
+		}
+	}
+}
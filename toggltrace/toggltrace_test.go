@@ -0,0 +1,216 @@
+package toggltrace
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jason0x43/go-toggl"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// newTestSession returns a session that sends its requests to a local server
+// and traces them with an in-memory exporter. The server records the
+// traceparent header of each request by path. Middleware is added between the
+// tracing middleware and the one sending requests to the server.
+func newTestSession(t *testing.T, handler http.HandlerFunc, middleware ...toggl.Middleware) (*toggl.Session, *tracetest.InMemoryExporter, *sdktrace.TracerProvider, map[string]string) {
+	t.Helper()
+	toggl.DisableLog()
+
+	var mu sync.Mutex
+	traceparents := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		traceparents[r.Method+" "+r.URL.Path] = r.Header.Get("traceparent")
+		mu.Unlock()
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	t.Cleanup(func() { provider.Shutdown(context.Background()) })
+
+	// redirect sends requests to the local server; it's added after the
+	// tracing middleware so spans still show the real Toggl URLs
+	redirect := func(next toggl.RoundTripFunc) toggl.RoundTripFunc {
+		return func(req *toggl.Request) (*toggl.Response, error) {
+			req.API = strings.Replace(req.API, "https://api.track.toggl.com", server.URL, 1)
+			return next(req)
+		}
+	}
+
+	session := toggl.OpenSession("token")
+	session.Use(Middleware(WithTracerProvider(provider), WithPropagator(propagation.TraceContext{})))
+	session.Use(middleware...)
+	session.Use(redirect)
+	return &session, exporter, provider, traceparents
+}
+
+func attributeValue(span tracetest.SpanStub, key attribute.Key) (attribute.Value, bool) {
+	for _, kv := range span.Attributes {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func TestMiddlewareSpans(t *testing.T) {
+	session, exporter, provider, traceparents := newTestSession(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "GET /api/v9/me/time_entries/current":
+			w.Write([]byte("null"))
+		case "POST /api/v9/workspaces/1/time_entries":
+			w.Write([]byte(`{"id":5,"workspace_id":1,"description":"Reviews","duration":-1}`))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "parent")
+	entry, err := session.WithContext(ctx).StartTimeEntry("Reviews", 1)
+	parent.End()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.ID != 5 {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 3 {
+		t.Fatalf("got %d spans, want 3", len(spans))
+	}
+	get, post := spans[0], spans[1]
+
+	if get.Name != "toggl.GetCurrentTimeEntry" {
+		t.Errorf("first span is %q", get.Name)
+	}
+	if post.Name != "toggl.StartTimeEntry" {
+		t.Errorf("second span is %q", post.Name)
+	}
+
+	traceID := parent.SpanContext().TraceID()
+	for _, span := range []tracetest.SpanStub{get, post} {
+		if span.Parent.SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("span %s isn't a child of the caller's span", span.Name)
+		}
+		if span.Status.Code == codes.Error {
+			t.Errorf("span %s has error status", span.Name)
+		}
+		if v, ok := attributeValue(span, "http.response.status_code"); !ok || v.AsInt64() != 200 {
+			t.Errorf("span %s has status code %v", span.Name, v.Emit())
+		}
+		if v, ok := attributeValue(span, RetryCountKey); !ok || v.AsInt64() != 0 {
+			t.Errorf("span %s has retry count %v", span.Name, v.Emit())
+		}
+	}
+
+	if v, ok := attributeValue(post, "http.request.method"); !ok || v.AsString() != "POST" {
+		t.Errorf("POST span has method %v", v.Emit())
+	}
+	if v, ok := attributeValue(post, "url.full"); !ok || v.AsString() != toggl.TogglAPI+"/workspaces/1/time_entries" {
+		t.Errorf("POST span has URL %v", v.Emit())
+	}
+	if v, ok := attributeValue(post, WorkspaceIDKey); !ok || v.AsInt64() != 1 {
+		t.Errorf("POST span has workspace ID %v", v.Emit())
+	}
+	if _, ok := attributeValue(get, WorkspaceIDKey); ok {
+		t.Error("GET span has a workspace ID")
+	}
+
+	for path, header := range traceparents {
+		if !strings.Contains(header, traceID.String()) {
+			t.Errorf("request %s has traceparent %q", path, header)
+		}
+	}
+}
+
+func TestMiddlewareErrors(t *testing.T) {
+	// POSTs aren't retried after server errors
+	session, exporter, _, _ := newTestSession(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusServiceUnavailable)
+	}, toggl.Retry(3, time.Millisecond))
+
+	if _, err := session.CreateProject("Web", 1); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	span := spans[0]
+
+	if span.Name != "toggl.CreateProject" {
+		t.Errorf("span is %q", span.Name)
+	}
+	if span.Status.Code != codes.Error {
+		t.Errorf("span status is %v", span.Status)
+	}
+	if v, ok := attributeValue(span, "http.response.status_code"); !ok || v.AsInt64() != http.StatusServiceUnavailable {
+		t.Errorf("span has status code %v", v.Emit())
+	}
+	if len(span.Events) == 0 || span.Events[0].Name != "exception" {
+		t.Errorf("span has no exception event: %+v", span.Events)
+	}
+	if v, ok := attributeValue(span, RetryCountKey); !ok || v.AsInt64() != 0 {
+		t.Errorf("span has retry count %v", v.Emit())
+	}
+}
+
+func TestMiddlewareRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures int
+		retries  int64
+		status   int64
+		code     codes.Code
+	}{
+		{"recovered", 2, 2, http.StatusOK, codes.Unset},
+		{"gave up", 5, 3, http.StatusTooManyRequests, codes.Error},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var mu sync.Mutex
+			attempts := 0
+			session, exporter, _, _ := newTestSession(t, func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				if attempts++; attempts <= test.failures {
+					http.Error(w, "slow down", http.StatusTooManyRequests)
+					return
+				}
+				w.Write([]byte("null"))
+			}, toggl.Retry(3, time.Millisecond))
+
+			session.GetCurrentTimeEntry()
+
+			spans := exporter.GetSpans()
+			if len(spans) != 1 {
+				t.Fatalf("got %d spans, want 1", len(spans))
+			}
+			span := spans[0]
+
+			if v, ok := attributeValue(span, RetryCountKey); !ok || v.AsInt64() != test.retries {
+				t.Errorf("span has retry count %v, want %d", v.Emit(), test.retries)
+			}
+			if v, ok := attributeValue(span, "http.response.status_code"); !ok || v.AsInt64() != test.status {
+				t.Errorf("span has status code %v, want %d", v.Emit(), test.status)
+			}
+			if span.Status.Code != test.code {
+				t.Errorf("span status is %v, want %v", span.Status.Code, test.code)
+			}
+		})
+	}
+}
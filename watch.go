@@ -0,0 +1,198 @@
+package toggl
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// TimerEventKind is a type of change to the running timer.
+type TimerEventKind int
+
+// Timer event kinds
+const (
+	// TimerStarted is sent when a timer starts running, including a timer
+	// that was already running when watching began.
+	TimerStarted TimerEventKind = iota
+
+	// TimerStopped is sent when the running timer stops. The event's Entry is
+	// the timer as it was last seen running.
+	TimerStopped
+
+	// TimerChanged is sent when the running timer's description, project,
+	// task, tags, billable flag or start time changes.
+	TimerChanged
+
+	// TimerTick is sent after each poll that finds the same timer still
+	// running.
+	TimerTick
+
+	// TimerError is sent when polling fails. Watching continues with a longer
+	// interval.
+	TimerError
+)
+
+var timerEventKindNames = map[TimerEventKind]string{
+	TimerStarted: "started",
+	TimerStopped: "stopped",
+	TimerChanged: "changed",
+	TimerTick:    "tick",
+	TimerError:   "error",
+}
+
+func (k TimerEventKind) String() string {
+	return timerEventKindNames[k]
+}
+
+// TimerEvent describes a change to the running timer.
+type TimerEvent struct {
+	Kind  TimerEventKind
+	Entry TimeEntry
+
+	// Changed lists the fields that changed for TimerChanged events:
+	// "description", "project", "task", "tags", "billable" and "start".
+	Changed []string
+
+	// Elapsed is how long the timer has been running.
+	Elapsed time.Duration
+
+	// Err is the polling error for TimerError events.
+	Err error
+}
+
+// WatchCurrentTimeEntry polls the running timer every interval and sends an
+// event on the returned channel whenever it starts, stops or changes, as well
+// as a tick for every poll in which it keeps running. When a poll fails, or
+// Toggl is rate limiting requests or has a server error, the interval is
+// doubled, up to 32 times the original, until a poll succeeds; a longer wait
+// requested by Toggl is respected. The channel is closed when ctx is done,
+// which also cancels a poll in progress.
+func (session *Session) WatchCurrentTimeEntry(ctx context.Context, interval time.Duration) <-chan TimerEvent {
+	events := make(chan TimerEvent)
+	session = session.WithContext(ctx)
+
+	go func() {
+		defer close(events)
+
+		send := func(event TimerEvent) bool {
+			select {
+			case events <- event:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		var last TimeEntry
+		delay := interval
+		timer := time.NewTimer(0)
+		defer timer.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-timer.C:
+			}
+
+			current, err := session.GetCurrentTimeEntry()
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				if !send(TimerEvent{Kind: TimerError, Entry: last, Err: err}) {
+					return
+				}
+				delay = backoff(delay, interval, err)
+				timer.Reset(delay)
+				continue
+			}
+			delay = interval
+
+			for _, event := range DiffTimers(last, current, time.Now()) {
+				if !send(event) {
+					return
+				}
+			}
+			last = current
+			timer.Reset(delay)
+		}
+	}()
+
+	return events
+}
+
+// backoff returns the delay before the next poll after one failed with err.
+func backoff(delay, interval time.Duration, err error) time.Duration {
+	if delay < 32*interval {
+		delay *= 2
+	}
+	var rerr *ResponseError
+	if errors.As(err, &rerr) && rerr.Temporary() {
+		if wait := rerr.RetryAfter(); wait > delay {
+			delay = wait
+		}
+	}
+	return delay
+}
+
+// DiffTimers returns the events describing the change from one observation of
+// the running timer to the next, as sent by WatchCurrentTimeEntry. An entry
+// with a zero ID means no timer was running.
+func DiffTimers(last, current TimeEntry, now time.Time) []TimerEvent {
+	var events []TimerEvent
+	elapsed := func(e TimeEntry) time.Duration {
+		return now.Sub(e.StartTime())
+	}
+
+	if last.ID != 0 && last.ID != current.ID {
+		events = append(events, TimerEvent{Kind: TimerStopped, Entry: last, Elapsed: elapsed(last)})
+	}
+	if current.ID == 0 {
+		return events
+	}
+
+	if last.ID != current.ID {
+		return append(events, TimerEvent{Kind: TimerStarted, Entry: current, Elapsed: elapsed(current)})
+	}
+
+	if changed := changedFields(last, current); len(changed) > 0 {
+		return append(events, TimerEvent{Kind: TimerChanged, Entry: current, Changed: changed, Elapsed: elapsed(current)})
+	}
+
+	return append(events, TimerEvent{Kind: TimerTick, Entry: current, Elapsed: elapsed(current)})
+}
+
+// changedFields returns the names of the fields that differ between two
+// versions of a time entry.
+func changedFields(a, b TimeEntry) []string {
+	var changed []string
+	sorted := func(tags []string) []string {
+		t := append([]string{}, tags...)
+		sort.Strings(t)
+		return t
+	}
+
+	if a.Description != b.Description {
+		changed = append(changed, "description")
+	}
+	if !reflect.DeepEqual(a.Pid, b.Pid) {
+		changed = append(changed, "project")
+	}
+	if !reflect.DeepEqual(a.Tid, b.Tid) {
+		changed = append(changed, "task")
+	}
+	if !reflect.DeepEqual(sorted(a.Tags), sorted(b.Tags)) {
+		changed = append(changed, "tags")
+	}
+	if a.Billable != b.Billable {
+		changed = append(changed, "billable")
+	}
+	if !a.StartTime().Equal(b.StartTime()) {
+		changed = append(changed, "start")
+	}
+
+	return changed
+}
@@ -0,0 +1,166 @@
+package toggl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestDiffTimers(t *testing.T) {
+	now := *testTime(10, 30)
+	running := func(id int, description string) TimeEntry {
+		return TimeEntry{ID: id, Wid: 1, Description: description, Start: testTime(10, 0), Duration: -1}
+	}
+	with := func(e TimeEntry, change func(*TimeEntry)) TimeEntry {
+		change(&e)
+		return e
+	}
+	pid := 7
+
+	tests := []struct {
+		name    string
+		last    TimeEntry
+		current TimeEntry
+		kinds   []TimerEventKind
+		changed []string
+	}{
+		{"idle", TimeEntry{}, TimeEntry{}, nil, nil},
+		{"started", TimeEntry{}, running(1, "Reviews"), []TimerEventKind{TimerStarted}, nil},
+		{"stopped", running(1, "Reviews"), TimeEntry{}, []TimerEventKind{TimerStopped}, nil},
+		{"switched", running(1, "Reviews"), running(2, "Deploy"), []TimerEventKind{TimerStopped, TimerStarted}, nil},
+		{"tick", running(1, "Reviews"), running(1, "Reviews"), []TimerEventKind{TimerTick}, nil},
+		{
+			"tags reordered",
+			with(running(1, "Reviews"), func(e *TimeEntry) { e.Tags = []string{"a", "b"} }),
+			with(running(1, "Reviews"), func(e *TimeEntry) { e.Tags = []string{"b", "a"} }),
+			[]TimerEventKind{TimerTick}, nil,
+		},
+		{
+			"description changed",
+			running(1, "Reviews"),
+			running(1, "Code reviews"),
+			[]TimerEventKind{TimerChanged}, []string{"description"},
+		},
+		{
+			"everything changed",
+			running(1, "Reviews"),
+			with(running(1, "Deploy"), func(e *TimeEntry) {
+				e.Pid = &pid
+				e.Tid = &pid
+				e.Tags = []string{"ops"}
+				e.Billable = true
+				e.Start = testTime(9, 45)
+			}),
+			[]TimerEventKind{TimerChanged},
+			[]string{"description", "project", "task", "tags", "billable", "start"},
+		},
+	}
+
+	for _, test := range tests {
+		events := DiffTimers(test.last, test.current, now)
+
+		var kinds []TimerEventKind
+		var changed []string
+		for _, event := range events {
+			kinds = append(kinds, event.Kind)
+			changed = append(changed, event.Changed...)
+		}
+		if fmt.Sprint(kinds) != fmt.Sprint(test.kinds) {
+			t.Errorf("%s: got events %v, want %v", test.name, kinds, test.kinds)
+			continue
+		}
+		if fmt.Sprint(changed) != fmt.Sprint(test.changed) {
+			t.Errorf("%s: got changes %v, want %v", test.name, changed, test.changed)
+		}
+
+		for _, event := range events {
+			want := test.current
+			if event.Kind == TimerStopped {
+				want = test.last
+			}
+			if event.Entry.ID != want.ID {
+				t.Errorf("%s: %v event has entry %d, want %d", test.name, event.Kind, event.Entry.ID, want.ID)
+			}
+			if elapsed := now.Sub(*want.Start); event.Elapsed != elapsed {
+				t.Errorf("%s: %v event has elapsed %v, want %v", test.name, event.Kind, event.Elapsed, elapsed)
+			}
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	responseError := func(status int, retryAfter string) error {
+		header := http.Header{}
+		if retryAfter != "" {
+			header.Set("Retry-After", retryAfter)
+		}
+		return fmt.Errorf("Error getting timer: %w", &ResponseError{StatusCode: status, Header: header})
+	}
+	interval := time.Second
+
+	tests := []struct {
+		name  string
+		delay time.Duration
+		err   error
+		want  time.Duration
+	}{
+		{"network error", time.Second, errors.New("connection refused"), 2 * time.Second},
+		{"doubles", 4 * time.Second, errors.New("connection refused"), 8 * time.Second},
+		{"capped", 32 * time.Second, errors.New("connection refused"), 32 * time.Second},
+		{"server error", time.Second, responseError(503, ""), 2 * time.Second},
+		{"rate limited", time.Second, responseError(429, "60"), time.Minute},
+		{"short retry after", 8 * time.Second, responseError(429, "1"), 16 * time.Second},
+		{"not temporary", time.Second, responseError(404, "60"), 2 * time.Second},
+	}
+
+	for _, test := range tests {
+		if got := backoff(test.delay, interval, test.err); got != test.want {
+			t.Errorf("%s: backoff(%v) = %v, want %v", test.name, test.delay, got, test.want)
+		}
+	}
+}
+
+func TestWatchCurrentTimeEntry(t *testing.T) {
+	session, api := newTestSession(t, TimeEntry{ID: 1, Wid: 1, Description: "Reviews", Start: testTime(10, 0), Duration: -1})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := session.WatchCurrentTimeEntry(ctx, time.Millisecond)
+
+	next := func() TimerEvent {
+		t.Helper()
+		select {
+		case event := <-events:
+			return event
+		case <-time.After(5 * time.Second):
+			t.Fatal("no event")
+			return TimerEvent{}
+		}
+	}
+
+	if event := next(); event.Kind != TimerStarted || event.Entry.ID != 1 {
+		t.Fatalf("first event is %v for %d", event.Kind, event.Entry.ID)
+	}
+
+	api.mu.Lock()
+	delete(api.entries, 1)
+	api.mu.Unlock()
+
+	for {
+		event := next()
+		if event.Kind == TimerTick {
+			continue
+		}
+		if event.Kind != TimerStopped || event.Entry.ID != 1 {
+			t.Fatalf("got %v event for %d, want stopped", event.Kind, event.Entry.ID)
+		}
+		break
+	}
+
+	cancel()
+	for range events {
+	}
+}
@@ -0,0 +1,190 @@
+/*
+Package watson converts between Toggl time entries and Watson's frames file.
+
+Watson stores completed frames in a JSON file (usually ~/.config/watson/frames)
+as an array of arrays:
+
+	[[1704186000, 1704191400, "acme", "4f2a...", ["review"], 1704191400]]
+
+Each frame holds a start time, stop time, project name, frame ID, tags and last
+update time, with times given in Unix seconds. Watson has no descriptions, so
+a time entry's description is kept as a tag starting with DescriptionPrefix,
+such as "description:Code review", which Records turns back into a description.
+*/
+package watson
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/jason0x43/go-toggl"
+	"github.com/jason0x43/go-toggl/importer"
+)
+
+// DefaultProject is used for exported entries that have no project, since
+// Watson requires every frame to have one.
+const DefaultProject = "toggl"
+
+// DescriptionPrefix starts the tag that holds a time entry's description.
+const DescriptionPrefix = "description:"
+
+// Frame is a completed Watson frame.
+type Frame struct {
+	Start   time.Time
+	Stop    time.Time
+	Project string
+	ID      string
+	Tags    []string
+	Updated time.Time
+}
+
+// MarshalJSON encodes a frame in Watson's array format.
+func (f Frame) MarshalJSON() ([]byte, error) {
+	tags := f.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	return json.Marshal([]interface{}{
+		f.Start.Unix(),
+		f.Stop.Unix(),
+		f.Project,
+		f.ID,
+		tags,
+		f.Updated.Unix(),
+	})
+}
+
+// UnmarshalJSON decodes a frame from Watson's array format.
+func (f *Frame) UnmarshalJSON(b []byte) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	if len(raw) < 4 {
+		return fmt.Errorf("invalid frame %s", b)
+	}
+
+	var start, stop float64
+	fields := []interface{}{&start, &stop, &f.Project, &f.ID}
+	for i, field := range fields {
+		if err := json.Unmarshal(raw[i], field); err != nil {
+			return fmt.Errorf("invalid frame %s: %v", b, err)
+		}
+	}
+	f.Start = time.Unix(int64(start), 0)
+	f.Stop = time.Unix(int64(stop), 0)
+	f.Updated = f.Stop
+
+	if len(raw) > 4 {
+		if err := json.Unmarshal(raw[4], &f.Tags); err != nil {
+			return fmt.Errorf("invalid frame tags %s: %v", b, err)
+		}
+	}
+	if len(raw) > 5 {
+		var updated float64
+		if err := json.Unmarshal(raw[5], &updated); err != nil {
+			return fmt.Errorf("invalid frame update time %s: %v", b, err)
+		}
+		f.Updated = time.Unix(int64(updated), 0)
+	}
+
+	return nil
+}
+
+// Read reads a frames file.
+func Read(r io.Reader) ([]Frame, error) {
+	var frames []Frame
+	if err := json.NewDecoder(r).Decode(&frames); err != nil {
+		return nil, fmt.Errorf("Error decoding frames: %v", err)
+	}
+	return frames, nil
+}
+
+// Write writes frames as a frames file.
+func Write(w io.Writer, frames []Frame) error {
+	if frames == nil {
+		frames = []Frame{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", " ")
+	return enc.Encode(frames)
+}
+
+// FrameID returns the stable frame ID used for the time entry with a given ID.
+func FrameID(entryID int) string {
+	return fmt.Sprintf("%032x", entryID)
+}
+
+// FromTimeEntries converts stopped time entries into frames. The description
+// of each entry is added as its first tag, prefixed with DescriptionPrefix.
+func FromTimeEntries(entries []toggl.TimeEntry, index toggl.Index) []Frame {
+	var frames []Frame
+	for _, e := range entries {
+		if e.IsRunning() || e.Start == nil {
+			continue
+		}
+
+		frame := Frame{
+			Start:   *e.Start,
+			Stop:    e.Start.Add(time.Duration(e.Duration) * time.Second),
+			Project: index.ProjectName(e.Pid),
+			ID:      FrameID(e.ID),
+			Updated: time.Now(),
+		}
+		if e.At != nil {
+			frame.Updated = *e.At
+		}
+		if frame.Project == "" {
+			frame.Project = DefaultProject
+		}
+		if e.Description != "" {
+			frame.Tags = append(frame.Tags, DescriptionPrefix+e.Description)
+		}
+		frame.Tags = append(frame.Tags, e.Tags...)
+
+		frames = append(frames, frame)
+	}
+	return frames
+}
+
+// Records converts frames into import records. Frames whose project names one
+// of the account's projects are assigned to it. A tag starting with
+// DescriptionPrefix becomes the description; frames without one use other
+// project names, except DefaultProject, as the description. The frame's other
+// tags are kept as tags.
+func Records(frames []Frame, account toggl.Account) []importer.Record {
+	projects := map[string]bool{}
+	for _, p := range account.Projects {
+		projects[strings.ToLower(p.Name)] = true
+	}
+
+	var records []importer.Record
+	for i, f := range frames {
+		record := importer.Record{
+			Line:  i + 1,
+			Start: f.Start,
+			End:   f.Stop,
+		}
+
+		described := false
+		for _, tag := range f.Tags {
+			if strings.HasPrefix(tag, DescriptionPrefix) && !described {
+				record.Description = strings.TrimPrefix(tag, DescriptionPrefix)
+				described = true
+			} else {
+				record.Tags = append(record.Tags, tag)
+			}
+		}
+
+		if projects[strings.ToLower(f.Project)] {
+			record.Project = f.Project
+		} else if !described && f.Project != DefaultProject {
+			record.Description = f.Project
+		}
+		records = append(records, record)
+	}
+	return records
+}
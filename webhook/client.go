@@ -0,0 +1,188 @@
+/*
+Package webhook manages Toggl webhook subscriptions and receives the events
+Toggl sends to them.
+
+A Client creates, lists, updates and deletes subscriptions through the Toggl
+webhooks API. A Handler is an http.Handler that verifies the signature of each
+request Toggl sends to a subscription's callback URL, answers validation
+requests, and passes decoded events to callbacks:
+
+	handler := &webhook.Handler{
+		Secret: subscription.Secret,
+		OnTimeEntry: func(action webhook.Action, entry toggl.TimeEntry, event webhook.Event) {
+			fmt.Println(action, entry.Description)
+		},
+	}
+	http.Handle("/toggl", handler)
+
+See https://developers.track.toggl.com/docs/webhooks_start for more
+information on Toggl's webhooks.
+*/
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/jason0x43/go-toggl"
+)
+
+// WebhooksAPI is the base URL of the Toggl webhooks API.
+const WebhooksAPI = "https://api.track.toggl.com/webhooks/api/v1"
+
+// EventFilter selects the events a subscription receives. Entity is a model
+// name such as "time_entry" or "project", and Action is "created", "updated"
+// or "deleted". Either may be "*" to match anything.
+type EventFilter struct {
+	Entity string `json:"entity"`
+	Action string `json:"action"`
+}
+
+// Subscription is a webhook subscription.
+type Subscription struct {
+	ID           int           `json:"subscription_id,omitempty"`
+	Wid          int           `json:"workspace_id"`
+	Uid          int           `json:"user_id,omitempty"`
+	Enabled      bool          `json:"enabled"`
+	Description  string        `json:"description"`
+	EventFilters []EventFilter `json:"event_filters"`
+	URLCallback  string        `json:"url_callback"`
+
+	// Secret is used to sign the requests sent to the callback URL. Toggl
+	// generates one if it's empty when the subscription is created.
+	Secret string `json:"secret,omitempty"`
+
+	ValidatedAt      *time.Time `json:"validated_at,omitempty"`
+	HasPendingEvents bool       `json:"has_pending_events,omitempty"`
+	CreatedAt        *time.Time `json:"created_at,omitempty"`
+	UpdatedAt        *time.Time `json:"updated_at,omitempty"`
+	DeletedAt        *time.Time `json:"deleted_at,omitempty"`
+}
+
+// Client is a client for the Toggl webhooks API.
+type Client struct {
+	APIToken string
+
+	// BaseURL is the URL of the webhooks API. It defaults to WebhooksAPI.
+	BaseURL string
+
+	// HTTPClient is used to make requests. It defaults to
+	// http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// NewClient returns a webhooks API client that uses a session's API token.
+func NewClient(session *toggl.Session) *Client {
+	return &Client{APIToken: session.APIToken}
+}
+
+// Subscriptions returns a workspace's subscriptions.
+func (c *Client) Subscriptions(wid int) ([]Subscription, error) {
+	var subs []Subscription
+	err := c.do("GET", fmt.Sprintf("/subscriptions/%d", wid), nil, &subs)
+	return subs, err
+}
+
+// CreateSubscription creates a subscription in the workspace given by the
+// subscription's Wid. The subscription must be validated before Toggl sends it
+// any events; a Handler does this automatically.
+func (c *Client) CreateSubscription(sub Subscription) (Subscription, error) {
+	var created Subscription
+	err := c.do("POST", fmt.Sprintf("/subscriptions/%d", sub.Wid), sub, &created)
+	return created, err
+}
+
+// UpdateSubscription changes a subscription's description, event filters,
+// callback URL, secret and enabled state.
+func (c *Client) UpdateSubscription(sub Subscription) (Subscription, error) {
+	var updated Subscription
+	err := c.do("PUT", fmt.Sprintf("/subscriptions/%d/%d", sub.Wid, sub.ID), sub, &updated)
+	return updated, err
+}
+
+// SetEnabled enables or disables a subscription.
+func (c *Client) SetEnabled(wid, id int, enabled bool) (Subscription, error) {
+	var updated Subscription
+	data := map[string]bool{"enabled": enabled}
+	err := c.do("PATCH", fmt.Sprintf("/subscriptions/%d/%d", wid, id), data, &updated)
+	return updated, err
+}
+
+// DeleteSubscription deletes a subscription.
+func (c *Client) DeleteSubscription(wid, id int) error {
+	return c.do("DELETE", fmt.Sprintf("/subscriptions/%d/%d", wid, id), nil, nil)
+}
+
+// Ping asks Toggl to send a ping event to a subscription's callback URL.
+func (c *Client) Ping(wid, id int) error {
+	return c.do("POST", fmt.Sprintf("/ping/%d/%d", wid, id), nil, nil)
+}
+
+// Validate validates a subscription with the code Toggl sent to its callback
+// URL. This is only needed if the callback didn't answer the validation
+// request itself.
+func (c *Client) Validate(wid, id int, code string) error {
+	return c.do("GET", fmt.Sprintf("/validate/%d/%d/%s", wid, id, code), nil, nil)
+}
+
+// EventFilters returns the entities that can be subscribed to and the actions
+// available for each.
+func (c *Client) EventFilters() (map[string][]string, error) {
+	var filters map[string][]string
+	err := c.do("GET", "/event_filters", nil, &filters)
+	return filters, err
+}
+
+// do makes a request to the webhooks API and decodes the response into
+// result, if it's not nil.
+func (c *Client) do(method, path string, data, result interface{}) error {
+	baseURL := c.BaseURL
+	if baseURL == "" {
+		baseURL = WebhooksAPI
+	}
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	var body io.Reader
+	if data != nil {
+		b, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, baseURL+path, body)
+	if err != nil {
+		return fmt.Errorf("Error making request: %v", err)
+	}
+	req.SetBasicAuth(c.APIToken, "api_token")
+	req.Header.Add("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("Error making request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("Error reading body: %v", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		return fmt.Errorf("Response error: %s: %s", resp.Status, bytes.TrimSpace(content))
+	}
+
+	if result != nil && len(content) > 0 {
+		if err := json.Unmarshal(content, result); err != nil {
+			return fmt.Errorf("Error decoding response: %v", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// recordedRequest is a request received by a test server.
+type recordedRequest struct {
+	Method string
+	Path   string
+	Body   map[string]interface{}
+	Token  string
+}
+
+// newTestClient returns a client for a server that records each request and
+// answers with the JSON in responses for its method and path.
+func newTestClient(t *testing.T, responses map[string]string) (*Client, *[]recordedRequest) {
+	t.Helper()
+	var requests []recordedRequest
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := recordedRequest{Method: r.Method, Path: r.URL.Path}
+		req.Token, _, _ = r.BasicAuth()
+		if body, _ := io.ReadAll(r.Body); len(body) > 0 {
+			if err := json.Unmarshal(body, &req.Body); err != nil {
+				t.Errorf("invalid request body %s: %v", body, err)
+			}
+		}
+		requests = append(requests, req)
+
+		response, ok := responses[r.Method+" "+r.URL.Path]
+		if !ok {
+			http.Error(w, `{"error":"not found"}`, http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(response))
+	}))
+	t.Cleanup(server.Close)
+
+	return &Client{APIToken: "token", BaseURL: server.URL}, &requests
+}
+
+func TestClientSubscriptions(t *testing.T) {
+	client, requests := newTestClient(t, map[string]string{
+		"GET /subscriptions/1":      `[{"subscription_id":2,"workspace_id":1,"enabled":true,"description":"hook"}]`,
+		"POST /subscriptions/1":     `{"subscription_id":3,"workspace_id":1,"secret":"generated"}`,
+		"PUT /subscriptions/1/3":    `{"subscription_id":3,"workspace_id":1,"description":"renamed"}`,
+		"PATCH /subscriptions/1/3":  `{"subscription_id":3,"workspace_id":1,"enabled":false}`,
+		"DELETE /subscriptions/1/3": ``,
+		"POST /ping/1/3":            ``,
+		"GET /validate/1/3/code":    ``,
+		"GET /event_filters":        `{"time_entry":["created","updated","deleted"]}`,
+	})
+
+	subs, err := client.Subscriptions(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subs) != 1 || subs[0].ID != 2 || !subs[0].Enabled || subs[0].Description != "hook" {
+		t.Errorf("unexpected subscriptions %+v", subs)
+	}
+
+	created, err := client.CreateSubscription(Subscription{
+		Wid:          1,
+		Description:  "hook",
+		URLCallback:  "https://example.com/toggl",
+		EventFilters: []EventFilter{{Entity: "time_entry", Action: "*"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.ID != 3 || created.Secret != "generated" {
+		t.Errorf("unexpected created subscription %+v", created)
+	}
+
+	created.Description = "renamed"
+	updated, err := client.UpdateSubscription(created)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Description != "renamed" {
+		t.Errorf("unexpected updated subscription %+v", updated)
+	}
+
+	disabled, err := client.SetEnabled(1, 3, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if disabled.Enabled {
+		t.Errorf("subscription is still enabled")
+	}
+
+	if err := client.Ping(1, 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Validate(1, 3, "code"); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.DeleteSubscription(1, 3); err != nil {
+		t.Fatal(err)
+	}
+
+	filters, err := client.EventFilters()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(filters["time_entry"]) != 3 {
+		t.Errorf("unexpected event filters %v", filters)
+	}
+
+	want := []string{
+		"GET /subscriptions/1",
+		"POST /subscriptions/1",
+		"PUT /subscriptions/1/3",
+		"PATCH /subscriptions/1/3",
+		"POST /ping/1/3",
+		"GET /validate/1/3/code",
+		"DELETE /subscriptions/1/3",
+		"GET /event_filters",
+	}
+	if len(*requests) != len(want) {
+		t.Fatalf("got %d requests, want %d", len(*requests), len(want))
+	}
+	for i, req := range *requests {
+		if got := req.Method + " " + req.Path; got != want[i] {
+			t.Errorf("request %d is %s, want %s", i, got, want[i])
+		}
+		if req.Token != "token" {
+			t.Errorf("request %d has token %q", i, req.Token)
+		}
+	}
+
+	if body := (*requests)[1].Body; body["url_callback"] != "https://example.com/toggl" {
+		t.Errorf("unexpected create body %v", body)
+	}
+	if body := (*requests)[3].Body; body["enabled"] != false {
+		t.Errorf("unexpected enable body %v", body)
+	}
+}
+
+func TestClientError(t *testing.T) {
+	client, _ := newTestClient(t, nil)
+
+	if _, err := client.Subscriptions(1); err == nil {
+		t.Error("expected an error for a 404 response")
+	}
+}
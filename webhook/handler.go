@@ -0,0 +1,204 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jason0x43/go-toggl"
+)
+
+// SignatureHeader is the request header containing the HMAC-SHA256 signature
+// of a webhook request's body.
+const SignatureHeader = "X-Webhook-Signature-256"
+
+// maxBodySize is the largest request body a Handler accepts.
+const maxBodySize = 1 << 20
+
+// Action is the change an event describes.
+type Action string
+
+// Event actions
+const (
+	Created Action = "created"
+	Updated Action = "updated"
+	Deleted Action = "deleted"
+)
+
+// Metadata describes the request that caused an event.
+type Metadata struct {
+	Action      Action `json:"action"`
+	Model       string `json:"model"`
+	Path        string `json:"path"`
+	RequestType string `json:"request_type"`
+}
+
+// Event is a webhook event. Payload holds the JSON of the changed entity, or
+// the string "ping" for pings and validation requests.
+type Event struct {
+	ID             int64           `json:"event_id"`
+	CreatedAt      time.Time       `json:"created_at"`
+	CreatorID      int             `json:"creator_id"`
+	SubscriptionID int             `json:"subscription_id"`
+	Timestamp      time.Time       `json:"timestamp"`
+	URLCallback    string          `json:"url_callback"`
+	Metadata       Metadata        `json:"metadata"`
+	Payload        json.RawMessage `json:"payload"`
+
+	// ValidationCode is set on the request Toggl sends to validate a new
+	// subscription.
+	ValidationCode    string `json:"validation_code,omitempty"`
+	ValidationCodeURL string `json:"validation_code_url,omitempty"`
+}
+
+// IsPing returns true if the event is a ping or a validation request.
+func (e Event) IsPing() bool {
+	var s string
+	return json.Unmarshal(e.Payload, &s) == nil && s == "ping"
+}
+
+// Handler receives webhook requests. It rejects requests without a valid
+// signature, answers validation requests, and calls the callback for the
+// event's model with the decoded payload. Callbacks are called synchronously,
+// so slow work should be handed off to keep Toggl from timing out. Events
+// without a matching callback are only passed to OnEvent.
+type Handler struct {
+	// Secret is the subscription's secret.
+	Secret string
+
+	// OnEvent, if set, is called for every event other than validation
+	// requests, before any model callback.
+	OnEvent func(event Event)
+
+	OnTimeEntry func(action Action, entry toggl.TimeEntry, event Event)
+	OnProject   func(action Action, project toggl.Project, event Event)
+	OnClient    func(action Action, client toggl.Client, event Event)
+	OnTag       func(action Action, tag toggl.Tag, event Event)
+
+	// OnError, if set, is called when a request is rejected or its payload
+	// can't be decoded.
+	OnError func(err error)
+}
+
+// ServeHTTP handles a webhook request.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.fail(w, http.StatusMethodNotAllowed, fmt.Errorf("unexpected %s request", r.Method))
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize))
+	if err != nil {
+		h.fail(w, http.StatusBadRequest, fmt.Errorf("Error reading body: %v", err))
+		return
+	}
+
+	if !Verify(h.Secret, body, r.Header.Get(SignatureHeader)) {
+		h.fail(w, http.StatusUnauthorized, fmt.Errorf("invalid signature"))
+		return
+	}
+
+	var event Event
+	if err := json.Unmarshal(body, &event); err != nil {
+		h.fail(w, http.StatusBadRequest, fmt.Errorf("Error decoding event: %v", err))
+		return
+	}
+
+	if event.ValidationCode != "" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"validation_code": event.ValidationCode})
+		return
+	}
+
+	if h.OnEvent != nil {
+		h.OnEvent(event)
+	}
+	if !event.IsPing() {
+		if err := h.dispatch(event); err != nil {
+			h.fail(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// dispatch decodes an event's payload and passes it to the callback for its
+// model.
+func (h *Handler) dispatch(event Event) error {
+	decode := func(v interface{}) error {
+		if err := json.Unmarshal(event.Payload, v); err != nil {
+			return fmt.Errorf("Error decoding %s payload: %v", event.Metadata.Model, err)
+		}
+		return nil
+	}
+	action := event.Metadata.Action
+
+	switch event.Metadata.Model {
+	case "time_entry":
+		if h.OnTimeEntry != nil {
+			var entry toggl.TimeEntry
+			if err := decode(&entry); err != nil {
+				return err
+			}
+			h.OnTimeEntry(action, entry, event)
+		}
+	case "project":
+		if h.OnProject != nil {
+			var project toggl.Project
+			if err := decode(&project); err != nil {
+				return err
+			}
+			h.OnProject(action, project, event)
+		}
+	case "client":
+		if h.OnClient != nil {
+			var client toggl.Client
+			if err := decode(&client); err != nil {
+				return err
+			}
+			h.OnClient(action, client, event)
+		}
+	case "tag":
+		if h.OnTag != nil {
+			var tag toggl.Tag
+			if err := decode(&tag); err != nil {
+				return err
+			}
+			h.OnTag(action, tag, event)
+		}
+	}
+
+	return nil
+}
+
+func (h *Handler) fail(w http.ResponseWriter, status int, err error) {
+	if h.OnError != nil {
+		h.OnError(err)
+	}
+	http.Error(w, err.Error(), status)
+}
+
+// Sign returns the signature header value for a request body.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify returns true if signature is a valid signature of body.
+func Verify(secret string, body []byte, signature string) bool {
+	sig, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil || len(sig) == 0 {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(sig, mac.Sum(nil))
+}
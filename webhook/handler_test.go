@@ -0,0 +1,171 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jason0x43/go-toggl"
+)
+
+const testSecret = "secret"
+
+// post sends a body to a handler through a local server, signed with
+// signature unless it's empty.
+func post(t *testing.T, h *Handler, body []byte, signature string) *http.Response {
+	t.Helper()
+	server := httptest.NewServer(h)
+	t.Cleanup(server.Close)
+
+	req, err := http.NewRequest("POST", server.URL, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signature != "" {
+		req.Header.Set(SignatureHeader, signature)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestHandlerSignature(t *testing.T) {
+	body := []byte(`{"event_id":1,"metadata":{"action":"created","model":"tag"},"payload":"ping"}`)
+
+	tests := []struct {
+		name      string
+		signature string
+		status    int
+	}{
+		{"valid", Sign(testSecret, body), http.StatusOK},
+		{"wrong secret", Sign("other", body), http.StatusUnauthorized},
+		{"not hex", "sha256=xyz", http.StatusUnauthorized},
+		{"missing", "", http.StatusUnauthorized},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var events, errors int
+			h := &Handler{
+				Secret:  testSecret,
+				OnEvent: func(Event) { events++ },
+				OnError: func(error) { errors++ },
+			}
+
+			resp := post(t, h, body, test.signature)
+			if resp.StatusCode != test.status {
+				t.Errorf("status is %d, want %d", resp.StatusCode, test.status)
+			}
+			if test.status == http.StatusOK && (events != 1 || errors != 0) {
+				t.Errorf("got %d events and %d errors for a valid request", events, errors)
+			}
+			if test.status != http.StatusOK && (events != 0 || errors != 1) {
+				t.Errorf("got %d events and %d errors for an invalid request", events, errors)
+			}
+		})
+	}
+}
+
+func TestHandlerValidation(t *testing.T) {
+	body := []byte(`{"event_id":1,"payload":"ping","validation_code":"abc123","validation_code_url":"https://example.com"}`)
+	called := false
+	h := &Handler{Secret: testSecret, OnEvent: func(Event) { called = true }}
+
+	resp := post(t, h, body, Sign(testSecret, body))
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status is %d", resp.StatusCode)
+	}
+	var answer map[string]string
+	if err := json.NewDecoder(resp.Body).Decode(&answer); err != nil {
+		t.Fatal(err)
+	}
+	if answer["validation_code"] != "abc123" {
+		t.Errorf("unexpected answer %v", answer)
+	}
+	if called {
+		t.Error("OnEvent was called for a validation request")
+	}
+}
+
+func TestHandlerDispatch(t *testing.T) {
+	var entries []toggl.TimeEntry
+	var projects []toggl.Project
+	var clients []toggl.Client
+	var tags []toggl.Tag
+	var actions []Action
+
+	h := &Handler{
+		Secret: testSecret,
+		OnTimeEntry: func(action Action, entry toggl.TimeEntry, event Event) {
+			actions = append(actions, action)
+			entries = append(entries, entry)
+		},
+		OnProject: func(action Action, project toggl.Project, event Event) {
+			actions = append(actions, action)
+			projects = append(projects, project)
+		},
+		OnClient: func(action Action, client toggl.Client, event Event) {
+			actions = append(actions, action)
+			clients = append(clients, client)
+		},
+		OnTag: func(action Action, tag toggl.Tag, event Event) {
+			actions = append(actions, action)
+			tags = append(tags, tag)
+		},
+	}
+
+	bodies := []string{
+		`{"metadata":{"action":"created","model":"time_entry"},"payload":{"id":1,"description":"Reviews","duration":-1}}`,
+		`{"metadata":{"action":"updated","model":"project"},"payload":{"id":2,"name":"Web"}}`,
+		`{"metadata":{"action":"deleted","model":"client"},"payload":{"id":3,"name":"Acme"}}`,
+		`{"metadata":{"action":"created","model":"tag"},"payload":{"id":4,"name":"meeting"}}`,
+		`{"metadata":{"action":"created","model":"workspace"},"payload":{"id":5}}`,
+	}
+	for _, body := range bodies {
+		resp := post(t, h, []byte(body), Sign(testSecret, []byte(body)))
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("status is %d for %s", resp.StatusCode, body)
+		}
+	}
+
+	if len(entries) != 1 || entries[0].ID != 1 || entries[0].Description != "Reviews" || !entries[0].IsRunning() {
+		t.Errorf("unexpected time entries %+v", entries)
+	}
+	if len(projects) != 1 || projects[0].Name != "Web" {
+		t.Errorf("unexpected projects %+v", projects)
+	}
+	if len(clients) != 1 || clients[0].Name != "Acme" {
+		t.Errorf("unexpected clients %+v", clients)
+	}
+	if len(tags) != 1 || tags[0].Name != "meeting" {
+		t.Errorf("unexpected tags %+v", tags)
+	}
+
+	want := []Action{Created, Updated, Deleted, Created}
+	if len(actions) != len(want) {
+		t.Fatalf("got actions %v, want %v", actions, want)
+	}
+	for i := range want {
+		if actions[i] != want[i] {
+			t.Errorf("action %d is %s, want %s", i, actions[i], want[i])
+		}
+	}
+}
+
+func TestHandlerBadPayload(t *testing.T) {
+	body := []byte(`{"metadata":{"action":"created","model":"time_entry"},"payload":{"id":"one"}}`)
+	h := &Handler{
+		Secret:      testSecret,
+		OnTimeEntry: func(Action, toggl.TimeEntry, Event) { t.Error("OnTimeEntry was called") },
+	}
+
+	resp := post(t, h, body, Sign(testSecret, body))
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("status is %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+}